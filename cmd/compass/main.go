@@ -1,18 +1,33 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/faisalahmedsifat/compass/internal/capture"
 	"github.com/faisalahmedsifat/compass/internal/config"
 	"github.com/faisalahmedsifat/compass/internal/processor"
+	"github.com/faisalahmedsifat/compass/internal/recategorize"
+	"github.com/faisalahmedsifat/compass/internal/schedule"
 	"github.com/faisalahmedsifat/compass/internal/server"
 	"github.com/faisalahmedsifat/compass/internal/storage"
 	"github.com/faisalahmedsifat/compass/pkg/types"
@@ -25,6 +40,28 @@ var (
 	GitCommit = "unknown" // Will be set during build
 	cfgFile   string
 	daemon    bool
+
+	recordOutput   string
+	recordDuration time.Duration
+	replayDatabase string
+	replayServe    bool
+
+	pauseFor time.Duration
+
+	overrideFor   time.Duration
+	overrideClear bool
+
+	rulesTestDays    int
+	rulesTestSamples int
+	rulesExplain     int64
+
+	recategorizeFrom   string
+	recategorizeTo     string
+	recategorizeDryRun bool
+
+	relabelFrom     string
+	relabelTo       string
+	relabelCategory string
 )
 
 func main() {
@@ -47,6 +84,9 @@ Examples:
   compass start              # Start tracking (foreground)
   compass start --daemon     # Start tracking (background)
   compass stop               # Stop tracking
+  compass pause --for 30m    # Pause tracking for 30 minutes
+  compass resume             # Resume tracking
+  compass override --for 2h  # Track outside working hours for 2 hours
   compass stats              # View quick stats
   compass dashboard          # Open dashboard in browser`,
 	Version: Version,
@@ -74,6 +114,38 @@ var stopCmd = &cobra.Command{
 	},
 }
 
+// pauseCmd pauses tracking
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause workspace tracking",
+	Long: `Stop capturing until 'compass resume', or for the duration given with --for.
+The time is recorded as Paused, and the pause survives restarting the tracker.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pauseTracking()
+	},
+}
+
+// resumeCmd resumes tracking after a pause
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume workspace tracking",
+	Long:  "Resume capturing after 'compass pause'.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resumeTracking()
+	},
+}
+
+// overrideCmd tracks outside the working-hours schedule
+var overrideCmd = &cobra.Command{
+	Use:   "override",
+	Short: "Track outside the working-hours schedule",
+	Long: `Track outside tracking.schedule for the duration given with --for, or until the
+schedule next starts. Use --clear to follow the schedule again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return overrideSchedule()
+	},
+}
+
 // statsCmd shows quick stats
 var statsCmd = &cobra.Command{
 	Use:   "stats",
@@ -114,6 +186,92 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// nativeHostCmd relays browser extension messages to the running tracker
+var nativeHostCmd = &cobra.Command{
+	Use:   "native-host",
+	Short: "Browser extension native messaging host",
+	Long: `Relay active-tab events from the Compass browser extension to the running tracker.
+Browsers start this command through a native messaging manifest; it is not meant to be run by hand.`,
+	// Browsers pass the extension origin and platform specific flags
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runNativeHost(os.Stdin, os.Stdout)
+	},
+}
+
+// recordCmd records window manager output for later replay
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record windows for replay",
+	Long: `Sample the window manager at the tracking interval and write what it reports,
+one JSON line per sample, for reproducing categorization with 'compass replay'.
+Recordings contain window titles, command lines and working directories.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return recordWindows()
+	},
+}
+
+// replayCmd runs the capture pipeline against a recording
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "Replay a recording through the tracker",
+	Long: `Run a recording made with 'compass record' through capture, categorization and
+storage on a virtual clock, as fast as the database allows. Use --serve to inspect
+the result through the API and dashboard.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return replayRecording(args[0])
+	},
+}
+
+// rulesCmd groups the categorization rule commands
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Work with categorization rules",
+	Long:  "Check categorization rules against the activities already recorded.",
+}
+
+// rulesTestCmd categorizes stored activities with a candidate rules file
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [rules-file]",
+	Short: "Test categorization rules against recorded activities",
+	Long: `Categorize the activities of the last --days again with a rules file (default
+~/.config/compass/rules.yaml) and report how the time per category, and the category
+of each activity, would change. With --explain, show how one activity is categorized:
+which rule matches and which conditions of the rules before it do not hold.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return testRules(args)
+	},
+}
+
+// recategorizeCmd categorizes stored activities again
+var recategorizeCmd = &cobra.Command{
+	Use:   "recategorize",
+	Short: "Categorize stored activities again",
+	Long: `Categorize the activities between --from and --to again with the current rules and
+store the new categories. Replaced categories are kept in the category history. Dates
+are YYYY-MM-DD, a --to date including that day, or RFC 3339 times. When the tracker is
+running it does the work and reports progress to the dashboard.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return recategorizeActivities()
+	},
+}
+
+// relabelCmd sets the category of activities by hand
+var relabelCmd = &cobra.Command{
+	Use:   "relabel [activity IDs...]",
+	Short: "Set the category of activities by hand",
+	Long: `Set the category of the given activities, or of those between --from and --to, to
+--category. Categories set by hand are kept when activities are recategorized, and each
+correction is recorded for training a categorizer. Dates are YYYY-MM-DD, a --to date
+including that day, or RFC 3339 times. Idle, away, locked and paused time keeps its
+category.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return relabelActivities(args)
+	},
+}
+
 // versionCmd shows detailed version information
 var versionCmd = &cobra.Command{
 	Use:   "version",
@@ -139,14 +297,54 @@ func init() {
 	// Start command flags
 	startCmd.Flags().BoolVar(&daemon, "daemon", false, "run in background")
 
+	// Pause command flags
+	pauseCmd.Flags().DurationVar(&pauseFor, "for", 0, "resume after this long (default until 'compass resume')")
+
+	// Override command flags
+	overrideCmd.Flags().DurationVar(&overrideFor, "for", 0, "track for this long (default until the schedule next starts)")
+	overrideCmd.Flags().BoolVar(&overrideClear, "clear", false, "end the override")
+
+	// Record and replay flags
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "compass-recording.jsonl", "recording file, - for stdout")
+	recordCmd.Flags().DurationVar(&recordDuration, "duration", 0, "stop after this long (default until interrupted)")
+	replayCmd.Flags().StringVar(&replayDatabase, "db", "compass-replay.db", "database to store replayed activities in")
+	replayCmd.Flags().BoolVar(&replayServe, "serve", false, "serve the API and dashboard after replaying")
+
+	// Rules test flags
+	rulesTestCmd.Flags().IntVar(&rulesTestDays, "days", 7, "test against the activities of this many days")
+	rulesTestCmd.Flags().IntVar(&rulesTestSamples, "samples", 3, "activities to show per category change")
+	rulesTestCmd.Flags().Int64Var(&rulesExplain, "explain", 0, "explain how the activity with this ID is categorized")
+
+	// Recategorize command flags
+	recategorizeCmd.Flags().StringVar(&recategorizeFrom, "from", "", "first day or time to categorize again")
+	recategorizeCmd.Flags().StringVar(&recategorizeTo, "to", "", "last day or time to categorize again (default now)")
+	recategorizeCmd.Flags().BoolVar(&recategorizeDryRun, "dry-run", false, "count the changes without storing them")
+	recategorizeCmd.MarkFlagRequired("from")
+
+	// Relabel command flags
+	relabelCmd.Flags().StringVar(&relabelFrom, "from", "", "first day or time to relabel")
+	relabelCmd.Flags().StringVar(&relabelTo, "to", "", "last day or time to relabel (default now)")
+	relabelCmd.Flags().StringVar(&relabelCategory, "category", "", "category to set")
+	relabelCmd.MarkFlagRequired("category")
+
 	// Add subcommands
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(overrideCmd)
 	rootCmd.AddCommand(statsCmd)
 	rootCmd.AddCommand(dashboardCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(nativeHostCmd)
+	rootCmd.AddCommand(recordCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(rulesCmd)
+	rootCmd.AddCommand(recategorizeCmd)
+	rootCmd.AddCommand(relabelCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
 }
 
 // initConfig reads in config file and ENV variables
@@ -155,6 +353,9 @@ func initConfig() {
 	if err := config.CreateDefaultConfigFile(); err != nil {
 		log.Printf("Warning: Could not create default config: %v", err)
 	}
+	if err := config.CreateDefaultRulesFile(processor.DefaultRules()); err != nil {
+		log.Printf("Warning: Could not create default rules: %v", err)
+	}
 }
 
 // runTracker starts the main tracking system
@@ -179,9 +380,17 @@ func runTracker() error {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer db.Close()
+	db.SetSplitCategoryTime(cfg.Categorization.SplitTime)
 
-	// Create categorizer
+	// Setup context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Create categorizer, following changes to the rules file
 	categorizer := processor.NewRuleBasedCategorizer()
+	if err := categorizer.WatchRules(ctx, config.GetRulesPath()); err != nil {
+		return fmt.Errorf("invalid categorization rules: %w", err)
+	}
 
 	// Create activity channel for real-time updates
 	activityChan := make(chan *types.Activity, 100)
@@ -191,10 +400,10 @@ func runTracker() error {
 
 	// Create capture engine
 	captureEngine := capture.NewCaptureEngine(cfg, db, categorizer, activityChan)
-
-	// Setup context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	webServer.SetTabReceiver(captureEngine)
+	webServer.SetPauseController(captureEngine)
+	webServer.SetScheduleController(captureEngine)
+	webServer.SetRecategorizer(recategorize.NewRunner(ctx, db, categorizer))
 
 	// Handle interrupt signals
 	sigChan := make(chan os.Signal, 1)
@@ -238,6 +447,102 @@ func runTracker() error {
 	return nil
 }
 
+// recordWindows writes window manager samples to the recording file
+func recordWindows() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	out := os.Stdout
+	if recordOutput != "-" {
+		file, err := os.Create(recordOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create recording: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if recordDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, recordDuration)
+		defer cancel()
+	}
+
+	if out != os.Stdout {
+		fmt.Printf("🧭 Recording windows every %v to %s, press Ctrl+C to stop\n", cfg.Tracking.Interval, recordOutput)
+	}
+	return capture.Record(ctx, cfg, out, cfg.Tracking.Interval)
+}
+
+// replayRecording feeds a recording through a capture engine on a virtual
+// clock and stores the result in the replay database
+func replayRecording(path string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	replay, err := capture.LoadRecording(path)
+	if err != nil {
+		return err
+	}
+
+	db, err := storage.NewDatabase(replayDatabase)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+	db.SetSplitCategoryTime(cfg.Categorization.SplitTime)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	categorizer := processor.NewRuleBasedCategorizer()
+	if err := categorizer.WatchRules(ctx, config.GetRulesPath()); err != nil {
+		return fmt.Errorf("invalid categorization rules: %w", err)
+	}
+
+	activityChan := make(chan *types.Activity, 100)
+	captureEngine := capture.NewCaptureEngineWithOptions(cfg, db, categorizer, activityChan, capture.EngineOptions{
+		WindowManager: replay,
+		Clock:         replay.Clock(),
+		Replay:        true,
+	})
+
+	engineDone := make(chan error, 1)
+	go func() {
+		engineDone <- captureEngine.Start(ctx)
+	}()
+	<-replay.Clock().Done()
+	cancel()
+	if err := <-engineDone; err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+
+	fmt.Printf("🧭 Replayed %s to %s into %s\n",
+		replay.Start().Format("2006-01-02 15:04:05"), replay.End().Format("15:04:05"), replayDatabase)
+
+	if stats, err := db.GetStats("day", replay.Start()); err == nil {
+		fmt.Printf("Total Active Time: %s\n", formatDurationForDisplay(stats.TotalTime))
+		for category, duration := range stats.ByCategory {
+			fmt.Printf("  %-15s %s\n", category, formatDurationForDisplay(duration))
+		}
+	}
+
+	if !replayServe {
+		return nil
+	}
+
+	serveCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Dashboard: http://%s:%s, press Ctrl+C to stop\n", cfg.Server.Host, cfg.Server.Port)
+	return server.NewServer(cfg.Server, db, activityChan).Start(serveCtx)
+}
+
 // showStats displays quick statistics in the terminal
 func showStats() error {
 	cfg, err := config.Load()
@@ -250,6 +555,7 @@ func showStats() error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
+	db.SetSplitCategoryTime(cfg.Categorization.SplitTime)
 
 	stats, err := db.GetStats("day", time.Now())
 	if err != nil {
@@ -282,6 +588,24 @@ func showStats() error {
 		}
 	}
 
+	if len(stats.ByRepo) > 0 {
+		fmt.Println("\nRepositories:")
+		for root, usage := range stats.ByRepo {
+			fmt.Printf("  %-30s %s\n", filepath.Base(root), formatDurationForDisplay(usage.TotalTime))
+			for branch, duration := range usage.ByBranch {
+				fmt.Printf("    %-28s %s\n", branch, formatDurationForDisplay(duration))
+			}
+		}
+	}
+
+	if len(stats.ByMonitor) > 1 {
+		fmt.Println("\nMonitors:")
+		for monitor, usage := range stats.ByMonitor {
+			fmt.Printf("  %-15s focused %s, reference %s\n", monitor,
+				formatDurationForDisplay(usage.FocusedTime), formatDurationForDisplay(usage.ReferenceTime))
+		}
+	}
+
 	// Show recent window details
 	fmt.Println("\nRecent Windows:")
 	activities, err := getRecentActivitiesForStats()
@@ -301,6 +625,359 @@ func showStats() error {
 	return nil
 }
 
+// rulesTestLimit bounds the activities a rules test reads
+const rulesTestLimit = 100000
+
+// testRules compares the stored categories of recent activities with those a
+// rules file gives them, or explains one activity
+func testRules(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	path := config.GetRulesPath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	categorizer, path, err := loadCategorizer(path, len(args) > 0)
+	if err != nil {
+		return err
+	}
+
+	db, err := storage.NewDatabase(cfg.Storage.Path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if rulesExplain != 0 {
+		return explainActivity(db, categorizer, rulesExplain)
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -rulesTestDays)
+	activities, err := db.GetActivities(from, to, rulesTestLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get activities: %w", err)
+	}
+	result := categorizer.Test(activities)
+
+	fmt.Printf("🧭 Compass Rules Test - %s\n", path)
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Activities: %d in the last %d days, %d change category\n", result.Activities, rulesTestDays, len(result.Flips))
+	if result.Manual > 0 {
+		fmt.Printf("Labelled by hand: %d, kept\n", result.Manual)
+	}
+	if result.Activities == 0 {
+		return nil
+	}
+
+	categories := testedCategories(result)
+	fmt.Println("\nTime per category:")
+	fmt.Printf("  %-15s %10s %10s %10s\n", "", "before", "after", "change")
+	for _, category := range categories {
+		before, after := result.Before[category], result.After[category]
+		fmt.Printf("  %-15s %10s %10s %10s\n", category,
+			formatDurationForDisplay(before), formatDurationForDisplay(after), formatDurationChange(after-before))
+	}
+
+	// Rows are the stored categories, columns the new ones
+	fmt.Println("\nActivities by category (rows before, columns after):")
+	fmt.Printf("  %-15s", "")
+	for _, category := range categories {
+		fmt.Printf(" %*s", columnWidth(category), category)
+	}
+	fmt.Println()
+	for _, before := range categories {
+		if result.Confusion[before] == nil {
+			continue
+		}
+		fmt.Printf("  %-15s", before)
+		for _, after := range categories {
+			cell := "."
+			if count := result.Confusion[before][after]; count > 0 {
+				cell = fmt.Sprint(count)
+			}
+			fmt.Printf(" %*s", columnWidth(after), cell)
+		}
+		fmt.Println()
+	}
+
+	printCategoryFlips(result.Flips)
+	return nil
+}
+
+// loadCategorizer creates a categorizer with the rules in path. Unless the
+// file was asked for, the built-in rules stand in for a missing file as they
+// do in the tracker. It also returns where the rules came from.
+func loadCategorizer(path string, required bool) (*processor.RuleBasedCategorizer, string, error) {
+	categorizer := processor.NewRuleBasedCategorizer()
+	rules, err := processor.LoadRules(path)
+	switch {
+	case err == nil:
+		categorizer.SetRules(rules)
+		return categorizer, path, nil
+	case !required && errors.Is(err, fs.ErrNotExist):
+		return categorizer, "built-in rules", nil
+	default:
+		return nil, "", fmt.Errorf("invalid categorization rules: %w", err)
+	}
+}
+
+// testedCategories lists the categories before and after a rules test
+func testedCategories(result *processor.RuleTestResult) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, durations := range []map[string]time.Duration{result.Before, result.After} {
+		for category := range durations {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// columnWidth sizes a column of the category matrix to its heading
+func columnWidth(category string) int {
+	if len(category) < 5 {
+		return 5
+	}
+	return len(category)
+}
+
+// printCategoryFlips shows the most recent activities of each category
+// change, the most frequent change first
+func printCategoryFlips(flips []processor.CategoryFlip) {
+	if len(flips) == 0 {
+		return
+	}
+
+	byChange := make(map[string][]processor.CategoryFlip)
+	var changes []string
+	for _, flip := range flips {
+		change := flip.From + " → " + flip.To
+		if byChange[change] == nil {
+			changes = append(changes, change)
+		}
+		byChange[change] = append(byChange[change], flip)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return len(byChange[changes[i]]) > len(byChange[changes[j]])
+	})
+
+	fmt.Println("\nChanged activities:")
+	for _, change := range changes {
+		group := byChange[change]
+		fmt.Printf("  %s (%d)\n", change, len(group))
+		for i := len(group) - 1; i >= 0 && i >= len(group)-rulesTestSamples; i-- {
+			activity := group[i].Activity
+			fmt.Printf("    #%-7d %s  %-20s %s\n", activity.ID, activity.Timestamp.Local().Format("Jan 2 15:04"),
+				truncateTitle(activity.AppName, 20), truncateTitle(activity.WindowTitle, 50))
+		}
+	}
+	fmt.Println("\nRun 'compass rules test --explain <id>' to see why an activity changes.")
+}
+
+// explainActivity shows how the rules categorize one stored activity
+func explainActivity(db *storage.Database, categorizer *processor.RuleBasedCategorizer, id int64) error {
+	activity, err := db.GetActivity(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧭 Activity #%d - %s\n", activity.ID, activity.Timestamp.Local().Format("January 2, 2006 15:04:05"))
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Stored category: %s\n", activity.Category)
+	if len(activity.AllWindows) > 0 {
+		fmt.Println("Windows:")
+	}
+	for _, w := range activity.AllWindows {
+		marker := " "
+		if w.IsActive {
+			marker = "*"
+		}
+		fmt.Printf("  %s %-20s %s\n", marker, truncateTitle(w.AppName, 20), truncateTitle(w.Title, 50))
+		if w.Command != "" {
+			fmt.Printf("      command: %s\n", w.Command)
+		}
+	}
+	if !activity.IsActive {
+		fmt.Printf("\n%s time is not categorized by rules.\n", activity.Category)
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Print(categorizer.Explain(processor.ActivitySnapshot(activity)))
+	return nil
+}
+
+// recategorizeActivities categorizes stored activities again, in the
+// running tracker when there is one
+func recategorizeActivities() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	from, err := parseDateFlag(recategorizeFrom, false)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+	to := time.Now()
+	if recategorizeTo != "" {
+		if to, err = parseDateFlag(recategorizeTo, true); err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("--from must be before --to")
+	}
+
+	path := fmt.Sprintf("/api/recategorize?from=%s&to=%s",
+		url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(to.Format(time.RFC3339)))
+	if recategorizeDryRun {
+		path += "&dry_run=true"
+	}
+
+	var status types.RecategorizeStatus
+	running, err := requestTracker(cfg, http.MethodPost, path, nil, &status)
+	if err != nil {
+		return err
+	}
+	if running {
+		fmt.Println("🧭 Recategorizing in the running tracker")
+		for status.Running {
+			printRecategorizeProgress(status)
+			time.Sleep(time.Second)
+			if running, err = requestTracker(cfg, http.MethodGet, "/api/recategorize", nil, &status); err != nil {
+				return err
+			}
+			if !running {
+				return fmt.Errorf("compass stopped while recategorizing")
+			}
+		}
+	} else {
+		categorizer, rulesPath, err := loadCategorizer(config.GetRulesPath(), false)
+		if err != nil {
+			return err
+		}
+		db, err := openDatabaseForUpdate(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		fmt.Printf("🧭 Recategorizing with %s\n", rulesPath)
+		status, _ = recategorize.Run(ctx, db, categorizer, from, to, recategorizeDryRun, printRecategorizeProgress)
+	}
+	printRecategorizeProgress(status)
+	fmt.Println()
+
+	if status.Error != "" {
+		return fmt.Errorf("recategorization stopped: %s", status.Error)
+	}
+	if status.DryRun {
+		fmt.Printf("Dry run: %d of %d activities would change category or confidence\n", status.Changed, status.Processed)
+	} else {
+		fmt.Printf("Recategorized %d activities, %d changed; the previous categories are kept in the category history\n",
+			status.Processed, status.Changed)
+	}
+	return nil
+}
+
+// relabelActivities sets the category of activities by hand, through the
+// running tracker when there is one
+func relabelActivities(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	request := types.RelabelRequest{Category: strings.TrimSpace(relabelCategory)}
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid activity ID %q", arg)
+		}
+		request.IDs = append(request.IDs, id)
+	}
+	if relabelFrom != "" {
+		from, err := parseDateFlag(relabelFrom, false)
+		if err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+		to := time.Now()
+		if relabelTo != "" {
+			if to, err = parseDateFlag(relabelTo, true); err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+		}
+		request.From, request.To = &from, &to
+	} else if relabelTo != "" {
+		return fmt.Errorf("--to needs --from")
+	}
+	if err := request.Validate(); err != nil {
+		return err
+	}
+
+	var result types.RelabelResult
+	running, err := requestTracker(cfg, http.MethodPatch, "/api/activities", request, &result)
+	if err != nil {
+		return err
+	}
+	if !running {
+		db, err := openDatabaseForUpdate(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if result.Relabeled, err = db.RelabelActivities(request); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("🧭 Relabelled %d activities as %s\n", result.Relabeled, request.Category)
+	return nil
+}
+
+// printRecategorizeProgress rewrites the progress line of a recategorization
+func printRecategorizeProgress(status types.RecategorizeStatus) {
+	fmt.Printf("\r  %d/%d activities, %d changed", status.Processed, status.Total, status.Changed)
+}
+
+// parseDateFlag parses a YYYY-MM-DD date in local time, or an RFC 3339 time.
+// With endOfDay a date means the end of that day.
+func parseDateFlag(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 time, got %q", value)
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// formatDurationChange formats a signed duration for terminal display
+func formatDurationChange(d time.Duration) string {
+	switch {
+	case d > 0:
+		return "+" + formatDurationForDisplay(d)
+	case d < 0:
+		return "-" + formatDurationForDisplay(-d)
+	}
+	return "0s"
+}
+
 // openDashboard opens the dashboard in the default browser
 func openDashboard() error {
 	cfg, err := config.Load()
@@ -346,21 +1023,250 @@ func showStatus() error {
 	fmt.Printf("Screenshots: %v\n", cfg.Tracking.CaptureScreenshots)
 
 	// Check if database exists and get stats
+	var overrideUntil time.Time
 	if db, err := storage.NewDatabase(cfg.Storage.Path); err == nil {
 		defer db.Close()
+		overrideUntil, _ = db.GetScheduleOverride()
 		if dbStats, err := db.GetDatabaseStats(); err == nil {
 			fmt.Printf("Total activities: %v\n", dbStats["total_activities"])
 			if first, ok := dbStats["first_activity"]; ok {
 				fmt.Printf("First activity: %v\n", first)
 			}
+			if saved, ok := dbStats["screenshot_bytes_saved"].(int64); ok {
+				fmt.Printf("Screenshots stored: %v (%.1f MB saved by deduplication)\n",
+					dbStats["screenshots_stored"], float64(saved)/1024/1024)
+			}
 		}
 	} else {
 		fmt.Println("Database: Not initialized")
 	}
 
+	// The configuration was validated on load
+	sched, _ := schedule.New(cfg.Tracking.Schedule)
+	fmt.Printf("Schedule: %s\n", formatScheduleStatus(sched.Status(time.Now(), overrideUntil)))
+
 	return nil
 }
 
+// formatScheduleStatus describes the working-hours schedule for the terminal
+func formatScheduleStatus(status types.ScheduleStatus) string {
+	if !status.Enabled {
+		return "not limited"
+	}
+
+	var description string
+	switch {
+	case status.InSchedule:
+		description = "in schedule"
+	case status.OverrideUntil != nil:
+		description = "outside schedule, overridden"
+	default:
+		description = "outside schedule, not tracking"
+	}
+
+	if status.NextTransition != nil {
+		change := "starts"
+		if status.Tracking {
+			change = "stops"
+		}
+		description += fmt.Sprintf(" (tracking %s %s)", change, status.NextTransition.Local().Format("Mon Jan 2 15:04"))
+	}
+	return description
+}
+
+// pauseTracking pauses the running tracker, or stores the pause for the
+// tracker to pick up when it is not running
+func pauseTracking() error {
+	if pauseFor < 0 {
+		return fmt.Errorf("pause duration cannot be negative")
+	}
+
+	path := "/api/pause"
+	if pauseFor > 0 {
+		path += "?for=" + pauseFor.String()
+	}
+
+	state, err := changePauseState(path, func(now time.Time) types.PauseState {
+		state := types.PauseState{Paused: true, Since: &now}
+		if pauseFor > 0 {
+			until := now.Add(pauseFor)
+			state.Until = &until
+		}
+		return state
+	})
+	if err != nil {
+		return err
+	}
+
+	if state.Until != nil {
+		fmt.Printf("🧭 Compass tracking paused until %s\n", state.Until.Local().Format("15:04:05"))
+	} else {
+		fmt.Println("🧭 Compass tracking paused, run 'compass resume' to resume")
+	}
+	return nil
+}
+
+// resumeTracking resumes the running tracker, or clears a stored pause when
+// it is not running
+func resumeTracking() error {
+	if _, err := changePauseState("/api/resume", func(time.Time) types.PauseState {
+		return types.PauseState{}
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println("🧭 Compass tracking resumed")
+	return nil
+}
+
+// changePauseState posts a pause change to the running tracker. When the
+// tracker cannot be reached, the state offline returns is saved to the
+// database instead.
+func changePauseState(path string, offline func(now time.Time) types.PauseState) (types.PauseState, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return types.PauseState{}, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var state types.PauseState
+	if running, err := requestTracker(cfg, http.MethodPost, path, nil, &state); running || err != nil {
+		return state, err
+	}
+
+	db, err := openDatabaseForUpdate(cfg)
+	if err != nil {
+		return types.PauseState{}, err
+	}
+	defer db.Close()
+
+	state = offline(time.Now())
+	if err := db.SavePauseState(state); err != nil {
+		return types.PauseState{}, fmt.Errorf("failed to save pause state: %w", err)
+	}
+	return state, nil
+}
+
+// overrideSchedule tracks outside the working-hours schedule, or ends the
+// override with --clear. The override is stored for the tracker to pick up
+// when it is not running.
+func overrideSchedule() error {
+	if overrideFor < 0 {
+		return fmt.Errorf("override duration cannot be negative")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	method, path := http.MethodPost, "/api/schedule/override"
+	if overrideClear {
+		method = http.MethodDelete
+	} else if overrideFor > 0 {
+		path += "?for=" + overrideFor.String()
+	}
+
+	var status types.ScheduleStatus
+	running, err := requestTracker(cfg, method, path, nil, &status)
+	if err != nil {
+		return err
+	}
+	if !running {
+		sched, err := schedule.New(cfg.Tracking.Schedule)
+		if err != nil {
+			return err
+		}
+		if sched == nil {
+			return fmt.Errorf("no tracking schedule is configured")
+		}
+
+		now := time.Now()
+		until := time.Time{}
+		if !overrideClear {
+			if until = now.Add(overrideFor); overrideFor == 0 {
+				if until = sched.NextStart(now); until.IsZero() {
+					return fmt.Errorf("the tracking schedule does not start again, give a duration with --for")
+				}
+			}
+		}
+
+		db, err := openDatabaseForUpdate(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := db.SaveScheduleOverride(until); err != nil {
+			return err
+		}
+		status = sched.Status(now, until)
+	}
+
+	if status.OverrideUntil != nil {
+		fmt.Printf("🧭 Tracking outside the schedule until %s\n", status.OverrideUntil.Local().Format("Mon 15:04"))
+	} else {
+		fmt.Println("🧭 Tracking follows the schedule again")
+	}
+	return nil
+}
+
+// requestTracker sends a request, with body as JSON unless it is nil, to the
+// running tracker's API and decodes the JSON response into result. It
+// reports false without an error only when nothing listens on the API port,
+// so callers never write state behind a tracker that is running but failed
+// to answer.
+func requestTracker(cfg *types.Config, method, path string, body, result interface{}) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := fmt.Sprintf("http://%s%s", net.JoinHostPort(cfg.Server.Host, cfg.Server.Port), path)
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("compass did not answer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return true, fmt.Errorf("compass rejected the request: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return true, fmt.Errorf("invalid response from compass: %w", err)
+	}
+	return true, nil
+}
+
+// openDatabaseForUpdate opens the database to store state for the tracker
+// to pick up, creating it if the tracker never ran
+func openDatabaseForUpdate(cfg *types.Config) (*storage.Database, error) {
+	if err := config.EnsureDatabaseDir(cfg.Storage.Path); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	db, err := storage.NewDatabase(cfg.Storage.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil
+}
+
 // formatDurationForDisplay formats duration for terminal display
 func formatDurationForDisplay(d time.Duration) string {
 	if d == 0 {
@@ -405,3 +1311,112 @@ func truncateTitle(title string, maxLen int) string {
 	}
 	return title[:maxLen-3] + "..."
 }
+
+// maxNativeMessageSize bounds messages read from the browser
+const maxNativeMessageSize = 1 << 20
+
+// runNativeHost speaks the browser native messaging protocol on in/out:
+// every message is a 32-bit native-endian length followed by JSON. Each tab
+// event is posted to the tracker's /api/browser/tab endpoint and answered
+// with {"ok": true} or {"ok": false, "error": ...}.
+func runNativeHost(in io.Reader, out io.Writer) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("http://%s/api/browser/tab", net.JoinHostPort(cfg.Server.Host, cfg.Server.Port))
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	// The browser starts the host, so the parent is the browser process
+	// owning the windows the tabs belong to
+	browserPID := os.Getppid()
+	browser := ""
+	if exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", browserPID)); err == nil {
+		browser = filepath.Base(exe)
+	}
+
+	for {
+		message, err := readNativeMessage(in)
+		if err == io.EOF {
+			// The extension disconnected
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var tab types.BrowserTab
+		if err := json.Unmarshal(message, &tab); err != nil {
+			if err := writeNativeReply(out, fmt.Errorf("invalid tab event: %w", err)); err != nil {
+				return err
+			}
+			continue
+		}
+		tab.PID = browserPID
+		if tab.Browser == "" {
+			tab.Browser = browser
+		}
+		if tab.Timestamp.IsZero() {
+			tab.Timestamp = time.Now()
+		}
+
+		if err := writeNativeReply(out, postTab(client, endpoint, &tab)); err != nil {
+			return err
+		}
+	}
+}
+
+// readNativeMessage reads one length-prefixed native messaging message
+func readNativeMessage(in io.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(in, binary.NativeEndian, &length); err != nil {
+		return nil, err
+	}
+	if length > maxNativeMessageSize {
+		return nil, fmt.Errorf("native message too large: %d bytes", length)
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(in, message); err != nil {
+		return nil, fmt.Errorf("failed to read native message: %w", err)
+	}
+	return message, nil
+}
+
+// writeNativeReply answers the extension with the outcome of a message
+func writeNativeReply(out io.Writer, result error) error {
+	reply := map[string]interface{}{"ok": result == nil}
+	if result != nil {
+		reply["error"] = result.Error()
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(out, binary.NativeEndian, uint32(len(data))); err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// postTab sends a tab event to the running tracker
+func postTab(client *http.Client, endpoint string, tab *types.BrowserTab) error {
+	body, err := json.Marshal(tab)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("compass is not running: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("compass rejected tab event: %s", resp.Status)
+	}
+	return nil
+}
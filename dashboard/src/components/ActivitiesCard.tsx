@@ -131,7 +131,12 @@ const ActivitiesCard: React.FC<ActivitiesCardProps> = ({ data, isLoading }) => {
               </p>
 
               <div className="flex items-center justify-between">
-                <span className={`inline-flex items-center px-2 py-1 rounded-full text-xs font-medium ${getCategoryColor(activity.category)}`}>
+                <span
+                  className={`inline-flex items-center px-2 py-1 rounded-full text-xs font-medium ${getCategoryColor(activity.category)}`}
+                  title={activity.manual
+                    ? 'Set by hand'
+                    : activity.candidates?.map((c) => `${c.category} ${Math.round(c.score * 100)}%`).join(', ')}
+                >
                   {activity.category}
                 </span>
                 
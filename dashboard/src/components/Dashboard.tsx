@@ -15,6 +15,8 @@ import StatsCard from './StatsCard';
 import ActivitiesCard from './ActivitiesCard';
 import CategoriesCard from './CategoriesCard';
 import ConnectionStatus from './ConnectionStatus';
+import PauseControl from './PauseControl';
+import RecategorizeProgress from './RecategorizeProgress';
 import FocusHeatmap from './FocusHeatmap';
 import AppEfficiencyRadar from './AppEfficiencyRadar';
 import EnergyProductivityScatter from './EnergyProductivityScatter';
@@ -62,7 +64,11 @@ const Dashboard: React.FC = () => {
                 <Compass className="h-8 w-8 text-primary-600" />
                 <h1 className="text-3xl font-bold text-gray-900">🧭 Compass Dashboard</h1>
               </div>
-              <ConnectionStatus isConnected={!healthError} />
+              <div className="flex items-center space-x-6">
+                <RecategorizeProgress />
+                <PauseControl />
+                <ConnectionStatus isConnected={!healthError} />
+              </div>
             </div>
             
             {/* Navigation and Controls */}
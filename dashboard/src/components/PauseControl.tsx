@@ -0,0 +1,55 @@
+import React from 'react';
+import { Pause, Play } from 'lucide-react';
+import { usePauseState, useSetPaused } from '../hooks/useCompassApi';
+
+const PauseControl: React.FC = () => {
+  const { data: pauseState, isError } = usePauseState();
+  const setPaused = useSetPaused();
+
+  if (isError || !pauseState) {
+    return null;
+  }
+
+  if (pauseState.paused) {
+    return (
+      <div className="flex items-center space-x-3">
+        <span className="text-sm text-amber-600 font-medium">
+          Paused{pauseState.until && ` until ${new Date(pauseState.until).toLocaleTimeString()}`}
+        </span>
+        <button
+          onClick={() => setPaused.mutate(null)}
+          disabled={setPaused.isPending}
+          className="flex items-center gap-1 px-3 py-1.5 rounded-lg text-sm font-medium bg-green-500 text-white hover:bg-green-600"
+        >
+          <Play className="h-4 w-4" />
+          Resume
+        </button>
+      </div>
+    );
+  }
+
+  return (
+    <div className="flex items-center space-x-2">
+      {(['30m', '1h'] as const).map((duration) => (
+        <button
+          key={duration}
+          onClick={() => setPaused.mutate(duration)}
+          disabled={setPaused.isPending}
+          className="px-3 py-1.5 rounded-lg text-sm font-medium bg-gray-100 text-gray-600 hover:bg-gray-200"
+        >
+          {duration}
+        </button>
+      ))}
+      <button
+        onClick={() => setPaused.mutate(undefined)}
+        disabled={setPaused.isPending}
+        className="flex items-center gap-1 px-3 py-1.5 rounded-lg text-sm font-medium bg-gray-100 text-gray-600 hover:bg-gray-200"
+      >
+        <Pause className="h-4 w-4" />
+        Pause
+      </button>
+    </div>
+  );
+};
+
+export default PauseControl;
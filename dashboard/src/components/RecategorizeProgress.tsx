@@ -0,0 +1,26 @@
+import React from 'react';
+import { RefreshCw } from 'lucide-react';
+import { useRecategorizeStatus } from '../hooks/useCompassApi';
+
+const RecategorizeProgress: React.FC = () => {
+  const { data: status } = useRecategorizeStatus();
+
+  if (!status?.running) {
+    return null;
+  }
+
+  const percent = status.total > 0 ? Math.round((status.processed / status.total) * 100) : 0;
+  return (
+    <div className="flex items-center space-x-2 text-sm text-blue-600" title={`${status.changed} changed so far`}>
+      <RefreshCw className="h-4 w-4 animate-spin" />
+      <span className="font-medium">
+        {status.dry_run ? 'Testing categories' : 'Recategorizing'} {percent}%
+      </span>
+      <div className="w-24 h-1.5 bg-blue-100 rounded-full overflow-hidden">
+        <div className="h-full bg-blue-500" style={{ width: `${percent}%` }} />
+      </div>
+    </div>
+  );
+};
+
+export default RecategorizeProgress;
@@ -100,7 +100,7 @@ const ScreenshotGallery: React.FC<ScreenshotGalleryProps> = ({ activities, isLoa
             >
               {/* Screenshot Image */}
               <img
-                src={`http://localhost:8080/api/screenshot/${activity.id}`}
+                src={`http://localhost:8080/api/screenshot/${activity.id}?size=thumb`}
                 alt={`Screenshot from ${activity.app_name}`}
                 className="w-full h-full object-cover group-hover:scale-105 transition-transform duration-300"
                 onError={(e) => {
@@ -1,5 +1,6 @@
-import { useQuery } from '@tanstack/react-query';
-import type { CurrentWorkspace, Activity, Stats, ApiInfo, AdvancedAnalytics, AppTransition, FocusPattern, EnergyMetrics } from '../types';
+import { useEffect } from 'react';
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import type { CurrentWorkspace, Activity, Stats, ApiInfo, AdvancedAnalytics, AppTransition, FocusPattern, EnergyMetrics, PauseState, RecategorizeStatus } from '../types';
 
 const API_BASE = 'http://localhost:8080';
 
@@ -72,6 +73,91 @@ export const useHealth = () => {
   });
 };
 
+export const usePauseState = () => {
+  const queryClient = useQueryClient();
+
+  // The tracker broadcasts pause_state whenever tracking is paused or resumed,
+  // including from the CLI and when a timed pause runs out
+  useEffect(() => {
+    const ws = new WebSocket('ws://localhost:8080/ws');
+    ws.onmessage = (event) => {
+      const message = JSON.parse(event.data);
+      if (message.type === 'pause_state') {
+        queryClient.setQueryData(['pauseState'], message.data);
+      }
+    };
+    return () => ws.close();
+  }, [queryClient]);
+
+  return useQuery<PauseState>({
+    queryKey: ['pauseState'],
+    queryFn: async () => {
+      const response = await fetch(`${API_BASE}/api/pause`);
+      if (!response.ok) {
+        throw new Error('Failed to fetch pause state');
+      }
+      return response.json();
+    },
+    refetchInterval: 60000,
+  });
+};
+
+// Pauses tracking for a duration such as '30m', or until resumed when
+// omitted; null resumes tracking
+export const useSetPaused = () => {
+  const queryClient = useQueryClient();
+
+  return useMutation<PauseState, Error, string | undefined | null>({
+    mutationFn: async (duration) => {
+      const path = duration === null
+        ? '/api/resume'
+        : `/api/pause${duration ? `?for=${duration}` : ''}`;
+      const response = await fetch(`${API_BASE}${path}`, { method: 'POST' });
+      if (!response.ok) {
+        throw new Error('Failed to change pause state');
+      }
+      return response.json();
+    },
+    onSuccess: (state) => queryClient.setQueryData(['pauseState'], state),
+  });
+};
+
+// Progress of categorizing stored activities again, started with
+// 'compass recategorize' or POST /api/recategorize
+export const useRecategorizeStatus = () => {
+  const queryClient = useQueryClient();
+
+  useEffect(() => {
+    const ws = new WebSocket('ws://localhost:8080/ws');
+    ws.onmessage = (event) => {
+      const message = JSON.parse(event.data);
+      if (message.type !== 'recategorize_status') {
+        return;
+      }
+      const status: RecategorizeStatus = message.data;
+      queryClient.setQueryData(['recategorizeStatus'], status);
+      // Stored categories changed, so everything derived from them is stale
+      if (!status.running && !status.dry_run) {
+        queryClient.invalidateQueries({ queryKey: ['activities'] });
+        queryClient.invalidateQueries({ queryKey: ['stats'] });
+        queryClient.invalidateQueries({ queryKey: ['advancedAnalytics'] });
+      }
+    };
+    return () => ws.close();
+  }, [queryClient]);
+
+  return useQuery<RecategorizeStatus>({
+    queryKey: ['recategorizeStatus'],
+    queryFn: async () => {
+      const response = await fetch(`${API_BASE}/api/recategorize`);
+      if (!response.ok) {
+        throw new Error('Failed to fetch recategorization status');
+      }
+      return response.json();
+    },
+  });
+};
+
 // Enhanced Analytics Hooks
 export const useAdvancedAnalytics = (period: string = 'week') => {
   return useQuery<AdvancedAnalytics>({
@@ -18,17 +18,26 @@ export interface CurrentWorkspace {
   all_windows: WindowInfo[];
   window_count: number;
   category: string;
+  candidates?: CategoryCandidate[];
   focus_time: string;
   context_switches: number;
   timestamp: string;
 }
 
+export interface CategoryCandidate {
+  category: string;
+  score: number;
+}
+
 export interface Activity {
   id: number;
   timestamp: string;
   app_name: string;
   window_title: string;
   category: string;
+  confidence?: number;
+  candidates?: CategoryCandidate[];
+  manual?: boolean;
   focus_duration: number;
   total_windows: number;
   all_windows?: WindowInfo[];
@@ -54,6 +63,25 @@ export interface Stats {
   longest_focus: number;
 }
 
+export interface PauseState {
+  paused: boolean;
+  since?: string;
+  until?: string;
+}
+
+export interface RecategorizeStatus {
+  running: boolean;
+  dry_run: boolean;
+  from: string;
+  to: string;
+  total: number;
+  processed: number;
+  changed: number;
+  started_at?: string;
+  finished_at?: string;
+  error?: string;
+}
+
 export interface ApiInfo {
   name: string;
   version: string;
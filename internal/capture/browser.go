@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/faisalahmedsifat/compass/internal/processor"
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// browserTabs keeps the active tab last reported by each browser process
+type browserTabs struct {
+	mu    sync.Mutex
+	byPID map[int]*types.BrowserTab
+	// latest is the last tab reported without a browser PID
+	latest *types.BrowserTab
+}
+
+// newBrowserTabs creates an empty tab registry
+func newBrowserTabs() *browserTabs {
+	return &browserTabs{
+		byPID: make(map[int]*types.BrowserTab),
+	}
+}
+
+// update records the active tab of a browser. A nil tab (e.g. a dropped
+// incognito tab) clears what was known about that browser, so its windows
+// are not attributed to the previous tab.
+func (t *browserTabs) update(pid int, tab *types.BrowserTab) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if pid > 0 {
+		t.byPID[pid] = tab
+	} else {
+		t.latest = tab
+	}
+}
+
+// tabFor returns the active tab of a browser window. Tabs are matched by the
+// browser's PID; tabs from hosts that could not report it are matched when
+// the window title contains the tab title, as browsers put it there.
+func (t *browserTabs) tabFor(window *types.Window) *types.BrowserTab {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tab, found := t.byPID[window.ProcessID]; found {
+		return tab
+	}
+	if t.latest != nil && t.latest.Title != "" && strings.Contains(window.Title, t.latest.Title) {
+		return t.latest
+	}
+	return nil
+}
+
+// UpdateTab records the active tab reported by a browser extension. In
+// events mode a tab switch in the focused browser starts a new segment.
+func (c *CaptureEngine) UpdateTab(tab *types.BrowserTab) {
+	if tab.Timestamp.IsZero() {
+		tab.Timestamp = c.clock.Now()
+	}
+
+	c.tabs.update(tab.PID, c.privacyFilter.FilterTab(tab))
+
+	select {
+	case c.tabChanges <- tab:
+	default:
+	}
+}
+
+// handleTabChange starts a new focus segment when the tab switch happened in
+// the focused browser. The extension's timestamp comes from another clock, so
+// the boundary is kept between the start of the running segment and now.
+func (c *CaptureEngine) handleTabChange(tab *types.BrowserTab) error {
+	if c.lastSnapshot == nil {
+		return nil
+	}
+
+	focused := c.lastSnapshot.ActiveWindow
+	inFocusedBrowser := tab.PID == focused.ProcessID ||
+		(tab.PID == 0 && processor.IsBrowser(focused))
+	if !inFocusedBrowser {
+		return nil
+	}
+
+	at := laterOf(tab.Timestamp, c.segmentStart)
+	if now := c.clock.Now(); at.After(now) {
+		at = now
+	}
+	return c.handleFocusChange(FocusEvent{Timestamp: at})
+}
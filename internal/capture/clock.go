@@ -0,0 +1,143 @@
+package capture
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the engine's source of time. The wall clock is used when
+// tracking; replays substitute a VirtualClock.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// wallClock is the real time
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) NewTicker(d time.Duration) Ticker {
+	return wallTicker{time.NewTicker(d)}
+}
+
+// wallTicker adapts time.Ticker to the Ticker interface
+type wallTicker struct {
+	ticker *time.Ticker
+}
+
+func (t wallTicker) C() <-chan time.Time { return t.ticker.C }
+
+func (t wallTicker) Stop() { t.ticker.Stop() }
+
+// VirtualClock runs from start to end as fast as its tickers are consumed.
+// A ticker holds one pending tick at a time. Time advances to that tick on
+// the first call to Now after it was received, so everything the receiver
+// does in response to a tick observes the same instant and replays are
+// deterministic. Done is closed once the clock has reached its last tick.
+type VirtualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	end     time.Time
+	tickers []*virtualTicker
+	done    chan struct{}
+}
+
+// NewVirtualClock creates a clock standing at start that stops at end
+func NewVirtualClock(start, end time.Time) *VirtualClock {
+	return &VirtualClock{
+		now:  start,
+		end:  end,
+		done: make(chan struct{}),
+	}
+}
+
+// Now returns the virtual time, advancing it to a tick that has been
+// received since the last call
+func (v *VirtualClock) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, t := range v.tickers {
+		if t.stopped || t.pending.IsZero() || len(t.ch) > 0 {
+			continue
+		}
+		// The pending tick was consumed
+		if t.pending.After(v.now) {
+			v.now = t.pending
+		}
+		t.pending = time.Time{}
+		v.schedule(t)
+	}
+	return v.now
+}
+
+// NewTicker returns a ticker firing every d of virtual time
+func (v *VirtualClock) NewTicker(d time.Duration) Ticker {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	t := &virtualTicker{clock: v, period: d, next: v.now, ch: make(chan time.Time, 1)}
+	v.tickers = append(v.tickers, t)
+	v.schedule(t)
+	return t
+}
+
+// Done is closed when no ticker has a tick left before the end time
+func (v *VirtualClock) Done() <-chan struct{} {
+	return v.done
+}
+
+// schedule queues the next tick of t, closing Done when the end is reached
+func (v *VirtualClock) schedule(t *virtualTicker) {
+	if t.period <= 0 {
+		return
+	}
+
+	t.next = t.next.Add(t.period)
+	if t.next.After(v.end) {
+		v.finish()
+		return
+	}
+	t.pending = t.next
+	t.ch <- t.next
+}
+
+// finish closes Done once every ticker has run out
+func (v *VirtualClock) finish() {
+	for _, t := range v.tickers {
+		if !t.stopped && !t.pending.IsZero() {
+			return
+		}
+	}
+	select {
+	case <-v.done:
+	default:
+		close(v.done)
+	}
+}
+
+// virtualTicker is a ticker of a VirtualClock; pending is the tick sitting
+// in ch, zero when there is none
+type virtualTicker struct {
+	clock   *VirtualClock
+	period  time.Duration
+	next    time.Time
+	pending time.Time
+	stopped bool
+	ch      chan time.Time
+}
+
+func (t *virtualTicker) C() <-chan time.Time { return t.ch }
+
+func (t *virtualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
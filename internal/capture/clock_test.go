@@ -0,0 +1,103 @@
+package capture
+
+import (
+	"testing"
+	"time"
+)
+
+var clockStart = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+// isDone reports whether a clock has reached its end
+func isDone(clock *VirtualClock) bool {
+	select {
+	case <-clock.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestVirtualClockAdvancesOnReceivedTicks(t *testing.T) {
+	clock := NewVirtualClock(clockStart, clockStart.Add(3*time.Second))
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Time stands still until a tick is received
+	for i := 0; i < 2; i++ {
+		if now := clock.Now(); !now.Equal(clockStart) {
+			t.Fatalf("Now() = %v before any tick, want %v", now, clockStart)
+		}
+	}
+
+	for i := 1; i <= 3; i++ {
+		if isDone(clock) {
+			t.Fatalf("clock done before tick %d", i)
+		}
+
+		want := clockStart.Add(time.Duration(i) * time.Second)
+		if tick := <-ticker.C(); !tick.Equal(want) {
+			t.Fatalf("tick %d = %v, want %v", i, tick, want)
+		}
+		if now := clock.Now(); !now.Equal(want) {
+			t.Fatalf("Now() after tick %d = %v, want %v", i, now, want)
+		}
+	}
+
+	if !isDone(clock) {
+		t.Error("clock not done after its last tick")
+	}
+	select {
+	case tick := <-ticker.C():
+		t.Errorf("got tick %v after the end", tick)
+	default:
+	}
+}
+
+func TestVirtualClockInterleavesTickers(t *testing.T) {
+	end := clockStart.Add(12 * time.Second)
+	clock := NewVirtualClock(clockStart, end)
+	fast := clock.NewTicker(2 * time.Second)
+	slow := clock.NewTicker(3 * time.Second)
+
+	last := clockStart
+	fastTicks, slowTicks := 0, 0
+	for !isDone(clock) {
+		select {
+		case <-fast.C():
+			fastTicks++
+		case <-slow.C():
+			slowTicks++
+		case <-time.After(time.Second):
+			t.Fatal("no tick pending before the clock is done")
+		}
+
+		now := clock.Now()
+		if now.Before(last) {
+			t.Fatalf("time went back from %v to %v", last, now)
+		}
+		last = now
+	}
+
+	if fastTicks != 6 || slowTicks != 4 {
+		t.Errorf("got %d fast and %d slow ticks, want 6 and 4", fastTicks, slowTicks)
+	}
+	if !last.Equal(end) {
+		t.Errorf("clock ended at %v, want %v", last, end)
+	}
+}
+
+func TestVirtualClockStoppedTickerDoesNotHoldEnd(t *testing.T) {
+	clock := NewVirtualClock(clockStart, clockStart.Add(2*time.Second))
+	running := clock.NewTicker(time.Second)
+	stopped := clock.NewTicker(time.Second)
+	stopped.Stop()
+
+	for i := 0; i < 2; i++ {
+		<-running.C()
+		clock.Now()
+	}
+
+	if !isDone(clock) {
+		t.Error("clock waits for a stopped ticker")
+	}
+}
@@ -0,0 +1,131 @@
+package capture
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// tcpEndpoint is the remote end of a socket
+type tcpEndpoint struct {
+	ip   string
+	port int
+}
+
+// detectConnections records the remote endpoints each window's process tree
+// is connected to. Windows of the same process share one lookup.
+func detectConnections(windows []*types.Window, tree processTree) {
+	var pids []int
+	seen := make(map[int]bool)
+	for _, w := range windows {
+		if w.ProcessID > 0 && !seen[w.ProcessID] {
+			seen[w.ProcessID] = true
+			pids = append(pids, w.ProcessID)
+		}
+	}
+	if len(pids) == 0 {
+		return
+	}
+
+	endpoints := readProcessConnections(pids, tree)
+	for _, w := range windows {
+		if sockets := endpoints[w.ProcessID]; len(sockets) > 0 {
+			w.Connections = aggregateConnections(sockets)
+		}
+	}
+}
+
+// aggregateConnections counts sockets per remote endpoint, most used first
+func aggregateConnections(sockets []tcpEndpoint) []types.Connection {
+	counts := make(map[tcpEndpoint]int)
+	for _, socket := range sockets {
+		counts[socket]++
+	}
+
+	connections := make([]types.Connection, 0, len(counts))
+	for endpoint, count := range counts {
+		connections = append(connections, types.Connection{
+			RemoteIP:   endpoint.ip,
+			RemotePort: endpoint.port,
+			Host:       localHosts.lookup(endpoint.ip),
+			Count:      count,
+		})
+	}
+
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].Count != connections[j].Count {
+			return connections[i].Count > connections[j].Count
+		}
+		if connections[i].RemoteIP != connections[j].RemoteIP {
+			return connections[i].RemoteIP < connections[j].RemoteIP
+		}
+		return connections[i].RemotePort < connections[j].RemotePort
+	})
+	return connections
+}
+
+// localHosts resolves connection addresses. Only the hosts file is consulted:
+// DNS lookups would leak the tracked endpoints and stall the capture loop.
+var localHosts = &hostsFile{path: "/etc/hosts"}
+
+// hostsFile caches the address to name mapping of a hosts file, reloading it
+// when its modification time changes
+type hostsFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	names   map[string]string
+}
+
+// lookup returns the first name listed for an address, or "" if none is
+func (h *hostsFile) lookup(ip string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return ""
+	}
+	if h.names == nil || !info.ModTime().Equal(h.modTime) {
+		h.names = parseHostsFile(h.path)
+		h.modTime = info.ModTime()
+	}
+	return h.names[ip]
+}
+
+// parseHostsFile reads "address name [aliases...]" lines. Addresses are
+// normalized so "::ffff:10.0.0.1" and "10.0.0.1" match the same entry.
+func parseHostsFile(path string) map[string]string {
+	names := make(map[string]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return names
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+		if _, exists := names[ip.String()]; !exists {
+			names[ip.String()] = fields[1]
+		}
+	}
+	return names
+}
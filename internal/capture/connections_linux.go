@@ -0,0 +1,118 @@
+//go:build linux
+
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpEstablished is the st column value of an established socket in /proc/net/tcp
+const tcpEstablished = "01"
+
+// readProcessConnections returns the remote endpoints of established TCP
+// connections held by each process and its descendants in tree. The socket
+// tables are read once for all processes.
+func readProcessConnections(pids []int, tree processTree) map[int][]tcpEndpoint {
+	sockets := readTCPSockets("/proc/net/tcp")
+	for inode, endpoint := range readTCPSockets("/proc/net/tcp6") {
+		sockets[inode] = endpoint
+	}
+	if len(sockets) == 0 {
+		return nil
+	}
+
+	connections := make(map[int][]tcpEndpoint, len(pids))
+	for _, pid := range pids {
+		for _, member := range append([]int{pid}, tree.descendants(pid)...) {
+			for _, inode := range socketInodes(member) {
+				if endpoint, ok := sockets[inode]; ok {
+					connections[pid] = append(connections[pid], endpoint)
+				}
+			}
+		}
+	}
+	return connections
+}
+
+// readTCPSockets maps the inode of every established socket in a
+// /proc/net/tcp table to its remote endpoint
+func readTCPSockets(path string) map[string]tcpEndpoint {
+	sockets := make(map[string]tcpEndpoint)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return sockets
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx:rx tr:when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpEstablished {
+			continue
+		}
+
+		address, portHex, found := strings.Cut(fields[2], ":")
+		if !found {
+			continue
+		}
+		ip := parseProcIP(address)
+		port, err := strconv.ParseUint(portHex, 16, 16)
+		if ip == nil || err != nil {
+			continue
+		}
+
+		sockets[fields[9]] = tcpEndpoint{ip: ip.String(), port: int(port)}
+	}
+	return sockets
+}
+
+// parseProcIP decodes an address from /proc/net/tcp, which is printed as
+// 32-bit words in host byte order
+func parseProcIP(address string) net.IP {
+	raw, err := hex.DecodeString(address)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	if v4 := ip.To4(); v4 != nil {
+		// IPv4 clients of dual-stack sockets appear as ::ffff:a.b.c.d
+		return v4
+	}
+	return ip
+}
+
+// socketInodes returns the inodes of the sockets a process has open. The
+// descriptors of other users' processes cannot be read and yield nothing.
+func socketInodes(pid int) []string {
+	dir := filepath.Join("/proc", strconv.Itoa(pid), "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var inodes []string
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if inode, found := strings.CutPrefix(target, "socket:["); found {
+			inodes = append(inodes, strings.TrimSuffix(inode, "]"))
+		}
+	}
+	return inodes
+}
@@ -0,0 +1,43 @@
+package capture
+
+import (
+	"image"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// differenceHash computes the 64-bit dHash of an image: the image is reduced
+// to 9x8 grey pixels and each bit records whether a pixel is brighter than
+// its right neighbour. Images that look alike have hashes differing in few
+// bits regardless of scaling and compression. The reduction filters the
+// whole image; sampling a few source pixels per cell, as bilinear scaling
+// does, would let a blinking cursor flip bits.
+func differenceHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.CatmullRom.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// hashDistance returns the number of bits in which two hashes differ
+func hashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// isDuplicateScreenshot reports whether a screenshot with the given hash is
+// close enough to the last stored one to reference it instead
+func (c *CaptureEngine) isDuplicateScreenshot(hash uint64) bool {
+	distance := c.config.Tracking.ScreenshotDedupDistance
+	return distance >= 0 && c.lastScreenshotID != 0 &&
+		hashDistance(hash, c.lastScreenshotHash) <= distance
+}
@@ -3,11 +3,16 @@ package capture
 import (
 	"context"
 	"fmt"
+	"image"
+	"io"
 	"log"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/faisalahmedsifat/compass/internal/schedule"
 	"github.com/faisalahmedsifat/compass/pkg/types"
 )
 
@@ -22,11 +27,83 @@ type CaptureEngine struct {
 	activityChan   chan *types.Activity
 	lastCapture    time.Time
 	lastScreenshot time.Time // Track when we last took a screenshot
+
+	// The last screenshot stored and its perceptual hash, which later
+	// screenshots are deduplicated against
+	lastScreenshotID   int64
+	lastScreenshotHash uint64
+
+	// Events mode state: the snapshot describing the focused window and
+	// when its current focus segment started
+	lastSnapshot *types.WorkspaceSnapshot
+	segmentStart time.Time
+
+	// Idle detection; idleSince is the last input before the current
+	// idle period, zero while the user is active
+	idleDetector IdleDetector
+	idleSince    time.Time
+
+	// Session state from sleep and lock events; awaySince is when the
+	// current away period started
+	sleeping  bool
+	locked    bool
+	awaySince time.Time
+
+	// Pause state; paused and pausedUntil belong to the capture loop,
+	// pauseState is the copy published to other goroutines
+	paused        bool
+	pausedUntil   time.Time
+	pauseMu       sync.Mutex
+	pauseState    types.PauseState
+	pauseRequests chan pauseRequest
+	pauseChanges  chan types.PauseState
+
+	// Working-hours schedule, nil when tracking is not limited to one;
+	// outsideSchedule and nextScheduleChange belong to the capture loop,
+	// scheduleOverride is shared with other goroutines
+	schedule           *schedule.Schedule
+	outsideSchedule    bool
+	nextScheduleChange time.Time
+	scheduleMu         sync.Mutex
+	scheduleOverride   time.Time
+	scheduleRequests   chan scheduleRequest
+
+	// Active browser tabs reported by the browser extension
+	tabs       *browserTabs
+	tabChanges chan *types.BrowserTab
+
+	// resources samples window process trees, nil when disabled
+	resources *resourceSampler
+
+	// enrichers run in order on every snapshot
+	enrichers []*registeredEnricher
+
+	// clock is the source of time; replayed windows do not belong to this
+	// machine, so their processes are not looked up
+	clock  Clock
+	replay bool
+}
+
+// EngineOptions replaces the platform dependencies of a capture engine
+type EngineOptions struct {
+	// WindowManager replaces the platform window manager
+	WindowManager types.WindowManager
+	// Clock replaces the wall clock
+	Clock Clock
+	// Replay marks windows as recorded elsewhere: their processes are not
+	// read from /proc and platform idle and session detection is disabled
+	Replay bool
 }
 
 // Storage interface for the capture engine
 type Storage interface {
 	SaveActivity(activity *types.Activity) error
+	MarkIdle(from, to time.Time) error
+	GetSetting(key string) (string, error)
+	GetPauseState() (types.PauseState, error)
+	SavePauseState(state types.PauseState) error
+	GetScheduleOverride() (time.Time, error)
+	SaveScheduleOverride(until time.Time) error
 }
 
 // Categorizer interface for activity categorization
@@ -34,22 +111,90 @@ type Categorizer interface {
 	Categorize(windows []types.Window) (string, float64)
 }
 
+// SnapshotCategorizer is implemented by categorizers that look at the whole
+// snapshot, including enricher metadata, rather than just its windows
+type SnapshotCategorizer interface {
+	CategorizeSnapshot(snapshot *types.WorkspaceSnapshot) (string, float64)
+}
+
+// CandidateCategorizer is implemented by categorizers that also rank every
+// category a snapshot could belong to
+type CandidateCategorizer interface {
+	CategorizeCandidates(snapshot *types.WorkspaceSnapshot) (string, float64, []types.CategoryCandidate)
+}
+
 // NewCaptureEngine creates a new capture engine
 func NewCaptureEngine(config *types.Config, storage Storage, categorizer Categorizer, activityChan chan *types.Activity) *CaptureEngine {
-	var windowMgr types.WindowManager
+	return NewCaptureEngineWithOptions(config, storage, categorizer, activityChan, EngineOptions{})
+}
+
+// NewCaptureEngineWithOptions creates a capture engine whose window manager
+// and clock can be injected, e.g. to replay a recording
+func NewCaptureEngineWithOptions(config *types.Config, storage Storage, categorizer Categorizer, activityChan chan *types.Activity, options EngineOptions) *CaptureEngine {
+	windowMgr := options.WindowManager
+	if windowMgr == nil {
+		// Use platform-specific implementation
+		windowMgr = newPlatformWindowManager(config.Tracking)
+	}
 
-	// Use platform-specific implementation
-	windowMgr = newPlatformWindowManager()
+	clock := options.Clock
+	if clock == nil {
+		clock = wallClock{}
+	}
 
-	return &CaptureEngine{
+	// Hashed titles are keyed with a salt created with the database
+	salt, err := storage.GetSetting("title_salt")
+	if err != nil {
+		log.Printf("Failed to read title hash salt: %v", err)
+	}
+
+	engine := &CaptureEngine{
 		windowMgr:     windowMgr,
 		storage:       storage,
 		categorizer:   categorizer,
-		privacyFilter: NewPrivacyFilter(config.Privacy),
+		privacyFilter: NewPrivacyFilter(config.Privacy, []byte(salt)),
 		interval:      config.Tracking.Interval,
 		config:        config,
 		activityChan:  activityChan,
+		tabs:          newBrowserTabs(),
+		tabChanges:    make(chan *types.BrowserTab, 16),
+		pauseRequests: make(chan pauseRequest),
+		pauseChanges:  make(chan types.PauseState, 16),
+		clock:         clock,
+		replay:        options.Replay,
+
+		scheduleRequests: make(chan scheduleRequest),
+	}
+
+	// Focus changes in events mode can trigger snapshots in quick
+	// succession, so resources are sampled at most every half interval
+	if config.Tracking.SampleResources && !options.Replay {
+		engine.resources = newResourceSampler(config.Tracking.Interval / 2)
+	}
+	engine.registerBuiltinEnrichers()
+
+	// Replays start from a fresh database and are not limited to the
+	// schedule
+	if !options.Replay {
+		engine.restorePause()
+		engine.setupSchedule()
 	}
+
+	// Idle detection is disabled with a zero threshold
+	if config.Tracking.IdleThreshold > 0 {
+		if options.Replay {
+			if detector, ok := windowMgr.(IdleDetector); ok {
+				engine.idleDetector = detector
+			}
+		} else {
+			engine.idleDetector = selectIdleDetector(windowMgr)
+		}
+		if engine.idleDetector == nil {
+			log.Printf("No idle detection available, away time will be counted as active")
+		}
+	}
+
+	return engine
 }
 
 // Start begins the capture process
@@ -63,27 +208,119 @@ func (c *CaptureEngine) Start(ctx context.Context) error {
 	c.lastCapture = time.Time{}
 	c.lastScreenshot = time.Time{}
 
-	ticker := time.NewTicker(c.interval)
+	// In events mode focus changes arrive on this channel; it stays nil
+	// (and never fires) when polling
+	var focusEvents <-chan FocusEvent
+	var tabChanges <-chan *types.BrowserTab
+	if c.config.Tracking.Mode == types.ModeEvents {
+		focusEvents = c.watchFocus(ctx)
+		if focusEvents != nil {
+			tabChanges = c.tabChanges
+		}
+	}
+
+	sessionEvents := c.watchSession(ctx)
+
+	ticker := c.clock.NewTicker(c.interval)
 	defer ticker.Stop()
 
 	// Take initial capture
-	if err := c.captureWorkspace(); err != nil {
+	if err := c.capture(focusEvents != nil); err != nil {
 		log.Printf("Initial capture failed: %v", err)
 	}
 
 	for {
 		select {
-		case <-ticker.C:
-			if err := c.captureWorkspace(); err != nil {
+		case <-ticker.C():
+			if err := c.capture(focusEvents != nil); err != nil {
 				log.Printf("Capture failed: %v", err)
 			}
+		case event, ok := <-focusEvents:
+			if !ok {
+				log.Printf("Focus events stopped, falling back to polling")
+				focusEvents = nil
+				tabChanges = nil
+				c.lastSnapshot = nil
+				continue
+			}
+			c.checkSchedule(event.Timestamp)
+			c.checkPauseExpiry(event.Timestamp)
+			if c.awayCategory() != "" {
+				continue
+			}
+			if err := c.handleFocusChange(event); err != nil {
+				log.Printf("Focus change capture failed: %v", err)
+			}
+		case tab := <-tabChanges:
+			c.checkSchedule(c.clock.Now())
+			c.checkPauseExpiry(c.clock.Now())
+			if c.awayCategory() != "" {
+				continue
+			}
+			if err := c.handleTabChange(tab); err != nil {
+				log.Printf("Tab change capture failed: %v", err)
+			}
+		case event, ok := <-sessionEvents:
+			if !ok {
+				sessionEvents = nil
+				continue
+			}
+			c.handleSessionEvent(event)
+		case request := <-c.pauseRequests:
+			c.handlePauseRequest(request)
+		case request := <-c.scheduleRequests:
+			c.handleScheduleRequest(request)
 		case <-ctx.Done():
 			log.Println("Stopping capture engine")
+			if away := c.awayCategory(); away != "" {
+				c.recordGap(away, c.awaySince, c.clock.Now())
+			} else if focusEvents != nil {
+				c.closeSegment(c.clock.Now())
+			}
+			if closer, ok := c.windowMgr.(io.Closer); ok {
+				closer.Close()
+			}
 			return nil
 		}
 	}
 }
 
+// watchFocus subscribes to focus change events, returning nil when the
+// window manager cannot provide them
+func (c *CaptureEngine) watchFocus(ctx context.Context) <-chan FocusEvent {
+	watcher, ok := c.windowMgr.(FocusWatcher)
+	if !ok {
+		log.Printf("Window backend does not support focus events, falling back to polling")
+		return nil
+	}
+
+	events, err := watcher.WatchFocus(ctx)
+	if err != nil {
+		log.Printf("Failed to watch focus changes, falling back to polling: %v", err)
+		return nil
+	}
+
+	log.Printf("Recording focus changes as they happen")
+	return events
+}
+
+// capture runs one periodic capture in the active mode
+func (c *CaptureEngine) capture(eventDriven bool) error {
+	c.checkSchedule(c.clock.Now())
+	c.checkPauseExpiry(c.clock.Now())
+
+	// Nothing to capture while suspended, locked, paused or outside the
+	// schedule
+	if c.awayCategory() != "" {
+		return nil
+	}
+
+	if eventDriven {
+		return c.captureSegment()
+	}
+	return c.captureWorkspace()
+}
+
 // CaptureOnce captures the current workspace state once
 func (c *CaptureEngine) CaptureOnce() (*types.WorkspaceSnapshot, error) {
 	return c.captureWorkspaceSnapshot()
@@ -91,21 +328,134 @@ func (c *CaptureEngine) CaptureOnce() (*types.WorkspaceSnapshot, error) {
 
 // captureWorkspace captures and stores the current workspace state
 func (c *CaptureEngine) captureWorkspace() error {
+	if c.checkIdle(c.clock.Now()) {
+		return nil
+	}
+
 	snapshot, err := c.captureWorkspaceSnapshot()
 	if err != nil {
 		return err
 	}
 
 	// Convert to activity record
-	activity := c.snapshotToActivity(snapshot)
+	return c.recordActivity(c.snapshotToActivity(snapshot))
+}
+
+// captureSegment takes a periodic snapshot in events mode. The focus segment
+// that has been running since the last snapshot or focus change is recorded
+// up to now, so long stretches of focus are persisted incrementally.
+func (c *CaptureEngine) captureSegment() error {
+	if c.checkIdle(c.clock.Now()) {
+		return nil
+	}
+
+	snapshot, err := c.captureWorkspaceSnapshot()
+	if err != nil {
+		return err
+	}
+
+	previous := c.lastSnapshot
+	c.lastSnapshot = snapshot
+	segmentStart := c.segmentStart
+	c.segmentStart = snapshot.Timestamp
+
+	if segmentStart.IsZero() {
+		// Nothing has elapsed yet
+		return nil
+	}
+
+	// A focus change we were not told about is attributed to the window
+	// that held focus at the previous snapshot
+	recorded := snapshot
+	if previous != nil && !sameWindow(&previous.ActiveWindow, &snapshot.ActiveWindow) {
+		recorded = previous
+	}
+
+	activity := c.segmentToActivity(recorded, segmentStart, snapshot.Timestamp)
+	// A screenshot and resource samples are stored only with the first
+	// activity of their snapshot
+	recorded.Screenshot = nil
+	recorded.Thumbnail = nil
+	recorded.ScreenshotID = 0
+	recorded.ResourceSamples = nil
+
+	return c.recordActivity(activity)
+}
+
+// handleFocusChange closes the running focus segment at the instant focus
+// changed and starts a new one from a fresh snapshot
+func (c *CaptureEngine) handleFocusChange(event FocusEvent) error {
+	// Focus moving without any input (e.g. a notification) is not activity
+	if c.checkIdle(event.Timestamp) {
+		return nil
+	}
+
+	c.closeSegment(event.Timestamp)
+
+	c.segmentStart = event.Timestamp
+	c.lastSnapshot = nil
+
+	snapshot, err := c.captureWorkspaceSnapshot()
+	if err != nil {
+		return err
+	}
+	c.lastSnapshot = snapshot
+
+	return nil
+}
+
+// closeSegment records the running focus segment up to end
+func (c *CaptureEngine) closeSegment(end time.Time) {
+	if c.lastSnapshot == nil {
+		return
+	}
+
+	activity := c.segmentToActivity(c.lastSnapshot, c.segmentStart, end)
+	c.lastSnapshot.Screenshot = nil
+	c.lastSnapshot.Thumbnail = nil
+	c.lastSnapshot.ScreenshotID = 0
+	c.lastSnapshot.ResourceSamples = nil
+	if err := c.recordActivity(activity); err != nil {
+		log.Printf("Failed to record focus segment: %v", err)
+	}
+}
+
+// recordGap stores an activity for a period nobody was working, such as
+// idle, locked or suspended time
+func (c *CaptureEngine) recordGap(category string, start, end time.Time) {
+	if !end.After(start) || category == offSchedule {
+		return
+	}
+
+	activity := &types.Activity{
+		Timestamp:     end,
+		StartTime:     start,
+		EndTime:       end,
+		AppName:       category,
+		IsActive:      false,
+		FocusDuration: int(end.Sub(start).Round(time.Second).Seconds()),
+		Category:      category,
+		Confidence:    1.0,
+	}
 
+	if err := c.recordActivity(activity); err != nil {
+		log.Printf("Failed to record %s time: %v", category, err)
+	}
+}
+
+// recordActivity stores an activity and publishes it to real-time subscribers
+func (c *CaptureEngine) recordActivity(activity *types.Activity) error {
 	// Store in database
 	if err := c.storage.SaveActivity(activity); err != nil {
 		return fmt.Errorf("failed to save activity: %w", err)
 	}
 
 	// Update last capture time AFTER successful save
-	c.lastCapture = snapshot.Timestamp
+	c.lastCapture = activity.Timestamp
+	if activity.Screenshot != nil {
+		c.lastScreenshotID = activity.ScreenshotID
+		c.lastScreenshotHash = activity.ScreenshotHash
+	}
 
 	// Send to real-time subscribers
 	select {
@@ -119,6 +469,12 @@ func (c *CaptureEngine) captureWorkspace() error {
 
 // captureWorkspaceSnapshot captures the current workspace state
 func (c *CaptureEngine) captureWorkspaceSnapshot() (*types.WorkspaceSnapshot, error) {
+	return c.snapshotWorkspace(true)
+}
+
+// snapshotWorkspace captures the current workspace state, taking a
+// screenshot when one is due and allowScreenshot is set
+func (c *CaptureEngine) snapshotWorkspace(allowScreenshot bool) (*types.WorkspaceSnapshot, error) {
 	// 1. Get all windows
 	windows, err := c.windowMgr.GetAllWindows()
 	if err != nil {
@@ -129,10 +485,15 @@ func (c *CaptureEngine) captureWorkspaceSnapshot() (*types.WorkspaceSnapshot, er
 		return nil, fmt.Errorf("no windows found")
 	}
 
-	// 2. Apply privacy filters
+	// 2. Read process details, which privacy filters and rules match on
+	if !c.replay {
+		enrichProcesses(windows)
+	}
+
+	// 3. Apply privacy filters
 	filteredWindows := c.privacyFilter.FilterWindows(windows)
 
-	// 3. Find active window
+	// 4. Find active window
 	var activeWindow types.Window
 	for _, w := range filteredWindows {
 		if w.IsActive {
@@ -141,40 +502,76 @@ func (c *CaptureEngine) captureWorkspaceSnapshot() (*types.WorkspaceSnapshot, er
 		}
 	}
 
-	// 4. Convert to slice of values for categorization
+	// 5. Convert to slice of values for categorization
 	windowValues := make([]types.Window, len(filteredWindows))
 	for i, w := range filteredWindows {
 		windowValues[i] = *w
 	}
 
-	// 5. Categorize activity
-	category, _ := c.categorizer.Categorize(windowValues)
-
-	// 6. Take screenshot (optional) - based on screenshot interval
-	var screenshot []byte
-	now := time.Now()
-	shouldTakeScreenshot := c.config.Tracking.CaptureScreenshots &&
-		(c.lastScreenshot.IsZero() || now.Sub(c.lastScreenshot) >= c.config.Tracking.ScreenshotInterval)
-
-	if shouldTakeScreenshot {
-		if data, err := c.windowMgr.TakeScreenshot(); err == nil {
-			screenshot = c.privacyFilter.BlurSensitive(data)
-			c.lastScreenshot = now // Update last screenshot time
+	// 6. Assign windows to monitors
+	var monitors []types.Monitor
+	if provider, ok := c.windowMgr.(MonitorProvider); ok {
+		if layout, err := provider.GetMonitors(); err == nil && len(layout) > 0 {
+			monitors = layout
+			assignMonitors(windowValues, monitors)
+			for _, w := range windowValues {
+				if w.IsActive {
+					activeWindow.Monitor = w.Monitor
+					break
+				}
+			}
 		}
 	}
 
+	now := c.clock.Now()
 	snapshot := &types.WorkspaceSnapshot{
-		Timestamp:    time.Now(),
+		Timestamp:    now,
 		ActiveWindow: activeWindow,
 		AllWindows:   windowValues,
 		WindowCount:  len(windowValues),
-		Category:     category,
-		Screenshot:   screenshot,
+		Monitors:     monitors,
+	}
+
+	// 7. Run the enrichers: terminal commands, connections, repository,
+	// browser tab, resource usage and any registered by the caller. The
+	// process tree is read once for all of them.
+	ctx := context.Background()
+	if !c.replay {
+		ctx = withProcessTree(ctx, readProcessTree())
+	}
+	snapshot = c.enrich(ctx, snapshot)
+
+	// 8. Categorize activity
+	c.categorize(snapshot)
+
+	// 9. Take screenshot (optional) - based on screenshot interval
+	shouldTakeScreenshot := allowScreenshot && c.config.Tracking.CaptureScreenshots &&
+		(c.lastScreenshot.IsZero() || now.Sub(c.lastScreenshot) >= c.config.Tracking.ScreenshotInterval)
+
+	if shouldTakeScreenshot {
+		if err := c.captureScreenshot(snapshot, windows); err == nil {
+			c.lastScreenshot = now // Update last screenshot time
+		} else {
+			log.Printf("Screenshot failed: %v", err)
+		}
 	}
 
 	return snapshot, nil
 }
 
+// categorize sets the category, confidence and candidates of a snapshot,
+// letting categorizers that understand snapshots see enricher metadata
+func (c *CaptureEngine) categorize(snapshot *types.WorkspaceSnapshot) {
+	switch categorizer := c.categorizer.(type) {
+	case CandidateCategorizer:
+		snapshot.Category, snapshot.Confidence, snapshot.Candidates = categorizer.CategorizeCandidates(snapshot)
+	case SnapshotCategorizer:
+		snapshot.Category, snapshot.Confidence = categorizer.CategorizeSnapshot(snapshot)
+	default:
+		snapshot.Category, snapshot.Confidence = c.categorizer.Categorize(snapshot.AllWindows)
+	}
+}
+
 // snapshotToActivity converts a workspace snapshot to an activity record
 func (c *CaptureEngine) snapshotToActivity(snapshot *types.WorkspaceSnapshot) *types.Activity {
 	// Calculate focus duration since last capture
@@ -208,38 +605,91 @@ func (c *CaptureEngine) snapshotToActivity(snapshot *types.WorkspaceSnapshot) *t
 	}
 
 	return &types.Activity{
-		Timestamp:     snapshot.Timestamp,
-		AppName:       snapshot.ActiveWindow.AppName,
-		WindowTitle:   snapshot.ActiveWindow.Title,
-		ProcessID:     snapshot.ActiveWindow.ProcessID,
-		IsActive:      true,
-		FocusDuration: focusDuration,
-		TotalWindows:  snapshot.WindowCount,
-		AllWindows:    snapshot.AllWindows,
-		Category:      snapshot.Category,
-		Confidence:    1.0, // Will be set by categorizer
-		Screenshot:    snapshot.Screenshot,
+		Timestamp:       snapshot.Timestamp,
+		StartTime:       snapshot.Timestamp.Add(-time.Duration(focusDuration) * time.Second),
+		EndTime:         snapshot.Timestamp,
+		AppName:         snapshot.ActiveWindow.AppName,
+		WindowTitle:     snapshot.ActiveWindow.Title,
+		ProcessID:       snapshot.ActiveWindow.ProcessID,
+		IsActive:        true,
+		FocusDuration:   focusDuration,
+		TotalWindows:    snapshot.WindowCount,
+		AllWindows:      snapshot.AllWindows,
+		Category:        snapshot.Category,
+		Confidence:      snapshot.Confidence,
+		Candidates:      snapshot.Candidates,
+		Screenshot:      snapshot.Screenshot,
+		Thumbnail:       snapshot.Thumbnail,
+		ScreenshotID:    snapshot.ScreenshotID,
+		ScreenshotHash:  snapshot.ScreenshotHash,
+		Monitor:         monitorName(snapshot.Monitors, snapshot.ActiveWindow.Monitor),
+		Monitors:        snapshot.Monitors,
+		Repository:      snapshot.Repository,
+		Tab:             snapshot.Tab,
+		Connections:     snapshot.ActiveWindow.Connections,
+		Metadata:        snapshot.Metadata,
+		ResourceSamples: snapshot.ResourceSamples,
+	}
+}
+
+// segmentToActivity converts a snapshot into an activity covering exactly
+// the focus segment [start, end]
+func (c *CaptureEngine) segmentToActivity(snapshot *types.WorkspaceSnapshot, start, end time.Time) *types.Activity {
+	return &types.Activity{
+		Timestamp:       end,
+		StartTime:       start,
+		EndTime:         end,
+		AppName:         snapshot.ActiveWindow.AppName,
+		WindowTitle:     snapshot.ActiveWindow.Title,
+		ProcessID:       snapshot.ActiveWindow.ProcessID,
+		IsActive:        true,
+		FocusDuration:   int(end.Sub(start).Round(time.Second).Seconds()),
+		TotalWindows:    snapshot.WindowCount,
+		AllWindows:      snapshot.AllWindows,
+		Category:        snapshot.Category,
+		Confidence:      snapshot.Confidence,
+		Candidates:      snapshot.Candidates,
+		Screenshot:      snapshot.Screenshot,
+		Thumbnail:       snapshot.Thumbnail,
+		ScreenshotID:    snapshot.ScreenshotID,
+		ScreenshotHash:  snapshot.ScreenshotHash,
+		Monitor:         monitorName(snapshot.Monitors, snapshot.ActiveWindow.Monitor),
+		Monitors:        snapshot.Monitors,
+		Repository:      snapshot.Repository,
+		Tab:             snapshot.Tab,
+		Connections:     snapshot.ActiveWindow.Connections,
+		Metadata:        snapshot.Metadata,
+		ResourceSamples: snapshot.ResourceSamples,
 	}
 }
 
 // PrivacyFilter handles privacy and security filtering
 type PrivacyFilter struct {
-	config          *types.PrivacyConfig
-	excludeApps     map[string]bool
-	excludePatterns []*regexp.Regexp
+	config             *types.PrivacyConfig
+	excludeApps        map[string]bool
+	excludeExecutables map[string]bool
+	excludePatterns    []*regexp.Regexp
+	rules              []*redactionRule
+	salt               []byte // Key of hashed titles
 }
 
-// NewPrivacyFilter creates a new privacy filter
-func NewPrivacyFilter(config *types.PrivacyConfig) *PrivacyFilter {
+// NewPrivacyFilter creates a new privacy filter. salt keys the hashes of
+// titles redacted by hash rules.
+func NewPrivacyFilter(config *types.PrivacyConfig, salt []byte) *PrivacyFilter {
 	filter := &PrivacyFilter{
-		config:      config,
-		excludeApps: make(map[string]bool),
+		config:             config,
+		excludeApps:        make(map[string]bool),
+		excludeExecutables: make(map[string]bool),
+		salt:               salt,
 	}
 
 	// Build exclude apps map for faster lookup
 	for _, app := range config.ExcludeApps {
 		filter.excludeApps[strings.ToLower(app)] = true
 	}
+	for _, executable := range config.ExcludeExecutables {
+		filter.excludeExecutables[executable] = true
+	}
 
 	// Compile exclude patterns
 	for _, pattern := range config.ExcludeTitles {
@@ -248,6 +698,16 @@ func NewPrivacyFilter(config *types.PrivacyConfig) *PrivacyFilter {
 		}
 	}
 
+	// Compile redaction rules; invalid rules are rejected when the
+	// configuration is loaded
+	for _, rule := range config.RedactionRules {
+		if compiled, err := compileRedactionRule(rule); err == nil {
+			filter.rules = append(filter.rules, compiled)
+		} else {
+			log.Printf("Skipping redaction rule: %v", err)
+		}
+	}
+
 	return filter
 }
 
@@ -257,15 +717,43 @@ func (f *PrivacyFilter) FilterWindows(windows []*types.Window) []*types.Window {
 
 	for _, w := range windows {
 		// Skip excluded apps
-		if f.isAppExcluded(w.AppName) {
+		if f.isAppExcluded(w.AppName) || f.isExecutableExcluded(w.Process) {
+			continue
+		}
+
+		title, drop := f.redactTitle(w.AppName, w.Title)
+		if drop {
 			continue
 		}
 
 		// Create a copy to avoid modifying original
 		window := *w
 
-		// Redact sensitive titles
-		window.Title = f.redactSensitiveTitle(window.Title)
+		// Redaction rules rewrite the title and the arguments of command
+		// lines. A window hidden by an excluded title is private: command
+		// lines, the working directory and remote hosts often name the same
+		// document or account, so they go too.
+		window.Title = title
+		if f.isTitleExcluded(w.Title) {
+			window.Redacted = true
+			window.Command = ""
+			window.Connections = nil
+			if window.Process != nil {
+				process := *window.Process
+				process.CommandLine = nil
+				process.WorkingDir = ""
+				window.Process = &process
+			}
+		} else {
+			if window.Command != "" {
+				window.Command = strings.Join(f.redactCommandLine(w.AppName, strings.Fields(w.Command)), " ")
+			}
+			if window.Process != nil && len(window.Process.CommandLine) > 0 {
+				process := *window.Process
+				process.CommandLine = f.redactCommandLine(w.AppName, process.CommandLine)
+				window.Process = &process
+			}
+		}
 
 		filtered = append(filtered, &window)
 	}
@@ -273,15 +761,53 @@ func (f *PrivacyFilter) FilterWindows(windows []*types.Window) []*types.Window {
 	return filtered
 }
 
-// BlurSensitive applies blur to sensitive screenshot regions
-func (f *PrivacyFilter) BlurSensitive(screenshot []byte) []byte {
-	if !f.config.BlurSensitive || len(screenshot) == 0 {
-		return screenshot
+// SensitiveRegions returns the screen areas of the windows FilterWindows
+// drops or hides as private; windows whose title a redaction rule merely
+// rewrote are not sensitive. ok is false when one of them has no known geometry or
+// covers a whole monitor, in which case no screenshot may be kept. With
+// blurring disabled there is nothing to redact.
+func (f *PrivacyFilter) SensitiveRegions(windows []*types.Window, monitors []types.Monitor) (regions []types.Rectangle, ok bool) {
+	if !f.config.BlurSensitive {
+		return nil, true
+	}
+
+	for _, w := range windows {
+		_, drop := f.redactTitle(w.AppName, w.Title)
+		if !f.isAppExcluded(w.AppName) && !f.isExecutableExcluded(w.Process) &&
+			!drop && !f.isTitleExcluded(w.Title) {
+			continue
+		}
+
+		if w.Position.Width <= 0 || w.Position.Height <= 0 {
+			return nil, false
+		}
+		for _, monitor := range monitors {
+			if covers(w.Position, monitor.Bounds) {
+				return nil, false
+			}
+		}
+		regions = append(regions, w.Position)
+	}
+	return regions, true
+}
+
+// BlurSensitive pixelates the sensitive regions of a screenshot. Regions are
+// in screen coordinates and are mapped onto the image through the monitor
+// layout, which the screenshot spans. A region covering the whole image is an
+// error, since nothing of the screenshot would be left.
+func (f *PrivacyFilter) BlurSensitive(screenshot image.Image, regions []types.Rectangle, monitors []types.Monitor) (image.Image, error) {
+	if !f.config.BlurSensitive || len(regions) == 0 {
+		return screenshot, nil
 	}
 
-	// For MVP, just return the screenshot as-is
-	// TODO: Implement OCR-based sensitive content detection and blurring
-	return screenshot
+	bounds := screenshot.Bounds()
+	rects := screenToImage(regions, monitors, bounds)
+	for _, rect := range rects {
+		if rect == bounds {
+			return nil, fmt.Errorf("sensitive window covers the screenshot")
+		}
+	}
+	return pixelate(screenshot, rects), nil
 }
 
 // isAppExcluded checks if an app should be excluded from tracking
@@ -289,12 +815,106 @@ func (f *PrivacyFilter) isAppExcluded(appName string) bool {
 	return f.excludeApps[strings.ToLower(appName)]
 }
 
-// redactSensitiveTitle redacts sensitive information from window titles
-func (f *PrivacyFilter) redactSensitiveTitle(title string) string {
+// FilterTab drops incognito tabs when configured and tabs whose title or URL
+// matches an excluded title pattern, returning nil for dropped tabs. The
+// redaction rules for the browser apply to the title and URL, so a tab
+// carries no more than the window title it appears in.
+func (f *PrivacyFilter) FilterTab(tab *types.BrowserTab) *types.BrowserTab {
+	if tab.Incognito && f.config.ExcludeIncognito {
+		return nil
+	}
+	for _, pattern := range f.excludePatterns {
+		if pattern.MatchString(tab.Title) || pattern.MatchString(tab.URL) {
+			return nil
+		}
+	}
+
+	title, dropTitle := f.redactTitle(tab.Browser, tab.Title)
+	url, dropURL := f.redactTitle(tab.Browser, tab.URL)
+	if dropTitle || dropURL {
+		return nil
+	}
+	if title == tab.Title && url == tab.URL {
+		return tab
+	}
+
+	redacted := *tab
+	redacted.Title = title
+	redacted.URL = url
+	return &redacted
+}
+
+// isExecutableExcluded checks if a process's executable is excluded by full
+// path or file name
+func (f *PrivacyFilter) isExecutableExcluded(process *types.ProcessInfo) bool {
+	if process == nil || process.Executable == "" {
+		return false
+	}
+	return f.excludeExecutables[process.Executable] ||
+		f.excludeExecutables[filepath.Base(process.Executable)]
+}
+
+// isTitleExcluded reports whether a title matches an excluded title pattern
+func (f *PrivacyFilter) isTitleExcluded(title string) bool {
 	for _, pattern := range f.excludePatterns {
 		if pattern.MatchString(title) {
-			return "[PRIVATE]"
+			return true
+		}
+	}
+	return false
+}
+
+// redactTitle redacts sensitive information from the title of a window of
+// appName. Titles matching an excluded title pattern are hidden entirely;
+// others go through the redaction rules for the app in order. drop is set
+// when a rule drops the window.
+func (f *PrivacyFilter) redactTitle(appName, title string) (redacted string, drop bool) {
+	if f.isTitleExcluded(title) {
+		return "[PRIVATE]", false
+	}
+	return f.applyRules(appName, title)
+}
+
+// redactCommandLine returns a copy of the command line of a process of
+// appName with its secrets masked. Values of secret-named options and
+// credential headers are always masked; every argument then goes through the
+// redaction rules for the app, and an argument a rule would drop is masked.
+func (f *PrivacyFilter) redactCommandLine(appName string, commandLine []string) []string {
+	redacted := make([]string, len(commandLine))
+	secretNext := false
+	for i, arg := range commandLine {
+		option := secretFlag.FindStringSubmatch(arg)
+		header := secretHeader.FindStringSubmatch(arg)
+		switch {
+		case secretNext:
+			redacted[i] = redactedPlaceholder
+		case option != nil && option[2] != "":
+			redacted[i] = option[1] + "=" + redactedPlaceholder
+		case header != nil:
+			redacted[i] = header[1] + ": " + redactedPlaceholder
+		default:
+			if rewritten, drop := f.applyRules(appName, arg); drop {
+				redacted[i] = redactedPlaceholder
+			} else {
+				redacted[i] = rewritten
+			}
+		}
+		// The value of an option without "=" is the next argument
+		secretNext = !secretNext && option != nil && option[2] == ""
+	}
+	return redacted
+}
+
+// applyRules runs text from appName through the redaction rules for the app
+// in order. drop is set when a rule drops it.
+func (f *PrivacyFilter) applyRules(appName, text string) (redacted string, drop bool) {
+	for _, rule := range f.rules {
+		if !rule.appliesTo(appName) {
+			continue
+		}
+		if text, drop = rule.apply(text, f.salt); drop {
+			return "", true
 		}
 	}
-	return title
+	return text, false
 }
@@ -0,0 +1,131 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// Enricher adds data to workspace snapshots, either to the windows and
+// snapshot fields it owns or as metadata. Enrichers run in ascending Order
+// after privacy filtering and before categorization, so rules see what they
+// add. Each runs on its own copy of the snapshot and its changes are kept only
+// when Enrich returns nil within Timeout, so a slow or failing enricher cannot
+// hold up or corrupt a capture. Enrichers must not modify values shared with
+// the original snapshot through pointers, such as a window's Process.
+type Enricher interface {
+	Name() string
+	Order() int
+	Timeout() time.Duration
+	Enrich(ctx context.Context, snapshot *types.WorkspaceSnapshot) error
+}
+
+// enricherFunc adapts a function to the Enricher interface
+type enricherFunc struct {
+	name    string
+	order   int
+	timeout time.Duration
+	enrich  func(ctx context.Context, snapshot *types.WorkspaceSnapshot) error
+}
+
+func (e *enricherFunc) Name() string           { return e.name }
+func (e *enricherFunc) Order() int             { return e.order }
+func (e *enricherFunc) Timeout() time.Duration { return e.timeout }
+
+func (e *enricherFunc) Enrich(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+	return e.enrich(ctx, snapshot)
+}
+
+// registeredEnricher tracks whether an enricher is still running. An
+// enricher that overran its timeout keeps running in the background and is
+// skipped until it returns, so it never runs concurrently with itself.
+type registeredEnricher struct {
+	Enricher
+	busy chan struct{}
+}
+
+// RegisterEnricher adds an enricher to the pipeline. It must be called
+// before Start.
+func (c *CaptureEngine) RegisterEnricher(enricher Enricher) {
+	c.enrichers = append(c.enrichers, &registeredEnricher{
+		Enricher: enricher,
+		busy:     make(chan struct{}, 1),
+	})
+	sort.SliceStable(c.enrichers, func(i, j int) bool {
+		return c.enrichers[i].Order() < c.enrichers[j].Order()
+	})
+}
+
+// enrich runs the pipeline, returning the snapshot with the changes of
+// every enricher that succeeded. Enrichers get contexts derived from ctx.
+func (c *CaptureEngine) enrich(ctx context.Context, snapshot *types.WorkspaceSnapshot) *types.WorkspaceSnapshot {
+	for _, enricher := range c.enrichers {
+		enriched, err := runEnricher(ctx, enricher, snapshot)
+		if err != nil {
+			log.Printf("Enricher %s failed: %v", enricher.Name(), err)
+			continue
+		}
+		snapshot = enriched
+	}
+	return snapshot
+}
+
+// runEnricher runs one enricher on a copy of the snapshot
+func runEnricher(parent context.Context, enricher *registeredEnricher, snapshot *types.WorkspaceSnapshot) (*types.WorkspaceSnapshot, error) {
+	select {
+	case enricher.busy <- struct{}{}:
+	default:
+		return nil, fmt.Errorf("still running from a previous capture")
+	}
+
+	ctx, cancel := context.WithTimeout(parent, enricher.Timeout())
+	defer cancel()
+
+	working := copySnapshot(snapshot)
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-enricher.busy }()
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- enricher.Enrich(ctx, working)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out after %v", enricher.Timeout())
+	}
+
+	// Enrichers update windows in AllWindows; the focused window is derived
+	// from them
+	for _, w := range working.AllWindows {
+		if w.IsActive {
+			working.ActiveWindow = w
+			break
+		}
+	}
+	return working, nil
+}
+
+// copySnapshot copies the parts of a snapshot enrichers modify
+func copySnapshot(snapshot *types.WorkspaceSnapshot) *types.WorkspaceSnapshot {
+	working := *snapshot
+	working.AllWindows = append([]types.Window(nil), snapshot.AllWindows...)
+	if snapshot.Metadata != nil {
+		working.Metadata = make(types.Metadata, len(snapshot.Metadata))
+		for key, value := range snapshot.Metadata {
+			working.Metadata[key] = value
+		}
+	}
+	return &working
+}
@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/internal/processor"
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// defaultEnricherTimeout bounds each built-in enricher; they only read /proc
+// and the filesystem
+const defaultEnricherTimeout = time.Second
+
+// registerBuiltinEnrichers adds the enrichers for the data sources Compass
+// knows about. Sources that read this machine's processes are skipped for
+// replayed windows.
+func (c *CaptureEngine) registerBuiltinEnrichers() {
+	if !c.replay {
+		c.RegisterEnricher(&enricherFunc{
+			name:    "command",
+			order:   10,
+			timeout: defaultEnricherTimeout,
+			enrich: func(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+				detectForegroundCommands(unredactedWindows(snapshot), snapshotProcessTree(ctx), c.privacyFilter)
+				return nil
+			},
+		})
+	}
+
+	if c.config.Privacy.TrackConnections && !c.replay {
+		c.RegisterEnricher(&enricherFunc{
+			name:    "connections",
+			order:   20,
+			timeout: defaultEnricherTimeout,
+			enrich: func(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+				detectConnections(unredactedWindows(snapshot), snapshotProcessTree(ctx))
+				return nil
+			},
+		})
+	}
+
+	if !c.replay {
+		c.RegisterEnricher(&enricherFunc{
+			name:    "repository",
+			order:   30,
+			timeout: defaultEnricherTimeout,
+			enrich: func(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+				if processor.IsDevelopmentTool(snapshot.ActiveWindow) {
+					snapshot.Repository = detectRepository(&snapshot.ActiveWindow, snapshotProcessTree(ctx))
+				}
+				return nil
+			},
+		})
+	}
+
+	c.RegisterEnricher(&enricherFunc{
+		name:    "tab",
+		order:   40,
+		timeout: defaultEnricherTimeout,
+		enrich: func(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+			if processor.IsBrowser(snapshot.ActiveWindow) {
+				snapshot.Tab = c.tabs.tabFor(&snapshot.ActiveWindow)
+			}
+			return nil
+		},
+	})
+
+	if c.resources != nil {
+		c.RegisterEnricher(&enricherFunc{
+			name:    "resources",
+			order:   50,
+			timeout: defaultEnricherTimeout,
+			enrich:  c.sampleResources,
+		})
+	}
+}
+
+// sampleResources samples the resource usage of every window's process tree,
+// stored with the snapshot's activity, and adds the focused window's sample
+// as "resources" metadata
+func (c *CaptureEngine) sampleResources(ctx context.Context, snapshot *types.WorkspaceSnapshot) error {
+	samples := c.resources.sample(snapshot.AllWindows, snapshotProcessTree(ctx), snapshot.Timestamp)
+	if len(samples) == 0 {
+		return nil
+	}
+
+	snapshot.ResourceSamples = samples
+	for _, sample := range samples {
+		if sample.ProcessID == snapshot.ActiveWindow.ProcessID {
+			snapshot.SetMetadata("resources", sample)
+			break
+		}
+	}
+	return nil
+}
+
+// unredactedWindows returns pointers to the snapshot's windows that may be
+// enriched with process details
+func unredactedWindows(snapshot *types.WorkspaceSnapshot) []*types.Window {
+	windows := make([]*types.Window, 0, len(snapshot.AllWindows))
+	for i := range snapshot.AllWindows {
+		if !snapshot.AllWindows[i].Redacted {
+			windows = append(windows, &snapshot.AllWindows[i])
+		}
+	}
+	return windows
+}
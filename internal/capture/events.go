@@ -0,0 +1,29 @@
+package capture
+
+import (
+	"context"
+	"time"
+)
+
+// FocusEvent reports that the focused window, or the title of the focused
+// window, changed at the given instant
+type FocusEvent struct {
+	Timestamp time.Time
+}
+
+// FocusWatcher is implemented by window managers that can report focus
+// changes as they happen instead of being polled
+type FocusWatcher interface {
+	// WatchFocus streams focus changes until ctx is cancelled or the
+	// underlying connection is closed, at which point the channel is closed
+	WatchFocus(ctx context.Context) (<-chan FocusEvent, error)
+}
+
+// sendFocusEvent delivers a focus event without blocking. A full channel
+// already holds a pending change, so dropping the event loses nothing.
+func sendFocusEvent(events chan<- FocusEvent, event FocusEvent) {
+	select {
+	case events <- event:
+	default:
+	}
+}
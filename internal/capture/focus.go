@@ -0,0 +1,55 @@
+package capture
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// focusTracker measures how long the current window has held focus.
+// It is shared by window managers that identify windows natively.
+type focusTracker struct {
+	mu               sync.Mutex
+	lastActiveWindow *types.Window
+	focusStartTime   time.Time
+}
+
+// newFocusTracker creates a new focus tracker
+func newFocusTracker() *focusTracker {
+	return &focusTracker{
+		focusStartTime: time.Now(),
+	}
+}
+
+// observe records the currently active window, restarting the focus timer
+// when it differs from the previously observed one
+func (t *focusTracker) observe(window *types.Window) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.lastActiveWindow != nil && sameWindow(t.lastActiveWindow, window) {
+		return
+	}
+
+	t.lastActiveWindow = window
+	t.focusStartTime = time.Now()
+}
+
+// duration returns how long the last observed window has been in focus
+func (t *focusTracker) duration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.lastActiveWindow == nil {
+		return 0
+	}
+	return time.Since(t.focusStartTime)
+}
+
+// sameWindow reports whether two windows describe the same focus target
+func sameWindow(a, b *types.Window) bool {
+	return a.AppName == b.AppName &&
+		a.Title == b.Title &&
+		a.ProcessID == b.ProcessID
+}
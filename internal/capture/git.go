@@ -0,0 +1,140 @@
+package capture
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// findRepository walks up from dir to the nearest git checkout and reads its
+// branch and remote straight from the git directory
+func findRepository(dir string) *types.Repository {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return nil
+	}
+
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir := dotGit
+			if !info.IsDir() {
+				// Worktrees and submodules have a "gitdir: <path>" file
+				if gitDir = readGitDirFile(dotGit); gitDir == "" {
+					return nil
+				}
+			}
+			return readRepository(dir, gitDir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// readGitDirFile resolves the git directory named by a .git file
+func readGitDirFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return gitDir
+}
+
+// readRepository reads the current branch from HEAD and the remote URL from
+// the config of a git directory
+func readRepository(root, gitDir string) *types.Repository {
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return nil
+	}
+
+	repo := &types.Repository{Root: root}
+
+	ref := strings.TrimSpace(string(head))
+	if branch, found := strings.CutPrefix(ref, "ref: refs/heads/"); found {
+		repo.Branch = branch
+	} else if len(ref) >= 7 {
+		// Detached HEAD holds a commit hash
+		repo.Branch = ref[:7]
+	}
+
+	// Linked worktrees share the config of the main repository
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	repo.Remote = readRemoteURL(filepath.Join(commonDir, "config"))
+
+	return repo
+}
+
+// readRemoteURL returns the URL of the "origin" remote, or of the first
+// remote when there is no origin, without any credentials it embeds
+func readRemoteURL(configPath string) string {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var section, first, origin string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		if !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		if first == "" {
+			first = value
+		}
+		if section == `[remote "origin"]` {
+			origin = value
+		}
+	}
+
+	if origin == "" {
+		origin = first
+	}
+	return stripCredentials(origin)
+}
+
+// stripCredentials removes user info such as access tokens from a URL.
+// scp-like addresses (git@host:path) are returned unchanged.
+func stripCredentials(remote string) string {
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.Scheme == "" || parsed.User == nil {
+		return remote
+	}
+	parsed.User = nil
+	return parsed.String()
+}
@@ -0,0 +1,74 @@
+package capture
+
+import (
+	"log"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// IdleDetector reports how long the user has not touched keyboard or mouse
+type IdleDetector interface {
+	IdleTime() (time.Duration, error)
+}
+
+// selectIdleDetector prefers the window manager's own idle counter and falls
+// back to the platform detector
+func selectIdleDetector(windowMgr types.WindowManager) IdleDetector {
+	if detector, ok := windowMgr.(IdleDetector); ok {
+		if _, err := detector.IdleTime(); err == nil {
+			return detector
+		}
+	}
+	return newPlatformIdleDetector()
+}
+
+// checkIdle records time spent away from the keyboard and reports whether
+// the user is idle right now, in which case no workspace capture is stored
+func (c *CaptureEngine) checkIdle(now time.Time) bool {
+	if c.idleDetector == nil {
+		return false
+	}
+
+	idle, err := c.idleDetector.IdleTime()
+	if err != nil {
+		return false
+	}
+	lastInput := now.Add(-idle)
+
+	if idle >= c.config.Tracking.IdleThreshold {
+		if c.idleSince.IsZero() {
+			c.idleSince = lastInput
+			log.Printf("No input since %s, recording idle time", lastInput.Format("15:04:05"))
+
+			// Captures between the last input and now were stored as active
+			if !c.lastCapture.IsZero() && c.lastCapture.After(lastInput) {
+				if err := c.storage.MarkIdle(lastInput, c.lastCapture); err != nil {
+					log.Printf("Failed to mark idle activities: %v", err)
+				}
+			}
+		}
+
+		c.recordGap(types.CategoryIdle, laterOf(c.lastCapture, c.idleSince), now)
+		c.lastSnapshot = nil
+		return true
+	}
+
+	if !c.idleSince.IsZero() {
+		// Back at the keyboard: everything up to the first input was idle
+		log.Printf("Input resumed after %v idle", lastInput.Sub(c.idleSince).Round(time.Second))
+		c.recordGap(types.CategoryIdle, laterOf(c.lastCapture, c.idleSince), lastInput)
+		c.idleSince = time.Time{}
+		c.segmentStart = lastInput
+	}
+
+	return false
+}
+
+// laterOf returns the later of two times
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,260 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	logindService   = "org.freedesktop.login1"
+	logindPath      = dbus.ObjectPath("/org/freedesktop/login1")
+	logindManager   = "org.freedesktop.login1.Manager"
+	logindSessionIf = "org.freedesktop.login1.Session"
+)
+
+// logindSession talks to systemd-logind about the session Compass runs in
+type logindSession struct {
+	conn *dbus.Conn
+	path dbus.ObjectPath
+}
+
+// newLogindSession connects to the system bus and resolves the current session
+func newLogindSession() (*logindSession, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+
+	session, err := newLogindSessionConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// newLogindSessionConn resolves the current session on an existing bus connection
+func newLogindSessionConn(conn *dbus.Conn) (*logindSession, error) {
+	manager := conn.Object(logindService, logindPath)
+
+	var path dbus.ObjectPath
+	err := manager.Call(logindManager+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&path)
+	if err != nil {
+		// Processes started outside the session (e.g. systemd user
+		// services) can still find it through XDG_SESSION_ID
+		id := os.Getenv("XDG_SESSION_ID")
+		if id == "" {
+			return nil, fmt.Errorf("failed to find logind session: %w", err)
+		}
+		if err := manager.Call(logindManager+".GetSession", 0, id).Store(&path); err != nil {
+			return nil, fmt.Errorf("failed to find logind session %s: %w", id, err)
+		}
+	}
+
+	return &logindSession{conn: conn, path: path}, nil
+}
+
+// Close closes the bus connection
+func (s *logindSession) Close() error {
+	return s.conn.Close()
+}
+
+// IdleTime derives the idle time from the session IdleHint, which the
+// desktop environment sets once it considers the user idle
+func (s *logindSession) IdleTime() (time.Duration, error) {
+	session := s.conn.Object(logindService, s.path)
+
+	hint, err := session.GetProperty(logindSessionIf + ".IdleHint")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read IdleHint: %w", err)
+	}
+	if idle, ok := hint.Value().(bool); !ok || !idle {
+		return 0, nil
+	}
+
+	since, err := session.GetProperty(logindSessionIf + ".IdleSinceHint")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read IdleSinceHint: %w", err)
+	}
+	usec, ok := since.Value().(uint64)
+	if !ok || usec == 0 {
+		return 0, nil
+	}
+
+	return time.Since(time.UnixMicro(int64(usec))), nil
+}
+
+// WatchSession subscribes to the logind PrepareForSleep signal and to the
+// Lock/Unlock signals and LockedHint changes of the session. A delay
+// inhibitor lock is held so the engine can close the open activity before
+// the machine actually suspends.
+func (s *logindSession) WatchSession(ctx context.Context) (<-chan SessionEvent, error) {
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchObjectPath(logindPath),
+			dbus.WithMatchInterface(logindManager),
+			dbus.WithMatchMember("PrepareForSleep"),
+		},
+		{
+			dbus.WithMatchObjectPath(s.path),
+			dbus.WithMatchInterface(logindSessionIf),
+		},
+		{
+			dbus.WithMatchObjectPath(s.path),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+	}
+	for _, match := range matches {
+		if err := s.conn.AddMatchSignal(match...); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to logind signals: %w", err)
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	s.conn.Signal(signals)
+
+	inhibitor := s.inhibitSleep()
+	events := make(chan SessionEvent, 16)
+
+	go func() {
+		defer close(events)
+		defer s.conn.RemoveSignal(signals)
+
+		for {
+			select {
+			case <-ctx.Done():
+				closeInhibitor(inhibitor)
+				return
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+
+				event, ok := s.sessionEvent(signal)
+				if !ok {
+					continue
+				}
+
+				switch event.Kind {
+				case SessionSleep:
+					// Suspend proceeds once the engine has handled the event
+					lock := inhibitor
+					inhibitor = -1
+					event.Release = func() { closeInhibitor(lock) }
+				case SessionWake:
+					if inhibitor < 0 {
+						inhibitor = s.inhibitSleep()
+					}
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					if event.Release != nil {
+						event.Release()
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// sessionEvent translates a logind signal into a session event
+func (s *logindSession) sessionEvent(signal *dbus.Signal) (SessionEvent, bool) {
+	event := SessionEvent{Timestamp: time.Now()}
+
+	switch signal.Name {
+	case logindManager + ".PrepareForSleep":
+		if len(signal.Body) == 0 {
+			return event, false
+		}
+		if start, _ := signal.Body[0].(bool); start {
+			event.Kind = SessionSleep
+		} else {
+			event.Kind = SessionWake
+		}
+	case logindSessionIf + ".Lock":
+		if signal.Path != s.path {
+			return event, false
+		}
+		event.Kind = SessionLock
+	case logindSessionIf + ".Unlock":
+		if signal.Path != s.path {
+			return event, false
+		}
+		event.Kind = SessionUnlock
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		// Desktop environments report the lock screen through LockedHint
+		if signal.Path != s.path || len(signal.Body) < 2 {
+			return event, false
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return event, false
+		}
+		hint, ok := changed["LockedHint"]
+		if !ok {
+			return event, false
+		}
+		if locked, _ := hint.Value().(bool); locked {
+			event.Kind = SessionLock
+		} else {
+			event.Kind = SessionUnlock
+		}
+	default:
+		return event, false
+	}
+
+	return event, true
+}
+
+// inhibitSleep takes a logind delay lock on sleep, returning the lock file
+// descriptor or -1 if none could be taken
+func (s *logindSession) inhibitSleep() int {
+	var fd dbus.UnixFD
+	err := s.conn.Object(logindService, logindPath).Call(logindManager+".Inhibit", 0,
+		"sleep", "Compass", "Record the current activity before suspend", "delay").Store(&fd)
+	if err != nil {
+		log.Printf("Failed to take sleep inhibitor lock: %v", err)
+		return -1
+	}
+	return int(fd)
+}
+
+// closeInhibitor releases a sleep inhibitor lock
+func closeInhibitor(fd int) {
+	if fd >= 0 {
+		syscall.Close(fd)
+	}
+}
+
+// newPlatformSessionWatcher returns the sleep and lock event source (Linux)
+func newPlatformSessionWatcher() SessionWatcher {
+	session, err := newLogindSession()
+	if err != nil {
+		log.Printf("Sleep and lock detection unavailable: %v", err)
+		return nil
+	}
+	return session
+}
+
+// newPlatformIdleDetector returns the fallback idle detector (Linux)
+func newPlatformIdleDetector() IdleDetector {
+	session, err := newLogindSession()
+	if err != nil {
+		return nil
+	}
+	return session
+}
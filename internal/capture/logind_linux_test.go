@@ -0,0 +1,258 @@
+//go:build linux
+
+package capture
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const testSessionPath = dbus.ObjectPath("/org/freedesktop/login1/session/_31")
+
+// privateBusConfig lets every connection own names and receive every signal
+const privateBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:tmpdir=/tmp</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*"/>
+    <allow receive_sender="*"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startPrivateBus runs a dbus-daemon for the test and returns its address.
+// The test is skipped when dbus-daemon is not installed.
+func startPrivateBus(t *testing.T) string {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+
+	config := filepath.Join(t.TempDir(), "bus.conf")
+	if err := os.WriteFile(config, []byte(privateBusConfig), 0o600); err != nil {
+		t.Fatalf("write bus config: %v", err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address=1")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// fakeLogind implements the logind manager methods Compass calls. The read
+// ends of pipes stand in for inhibitor lock file descriptors.
+type fakeLogind struct {
+	mu         sync.Mutex
+	inhibitors []*os.File
+}
+
+func (l *fakeLogind) GetSessionByPID(pid uint32) (dbus.ObjectPath, *dbus.Error) {
+	return testSessionPath, nil
+}
+
+func (l *fakeLogind) Inhibit(what, who, why, mode string) (dbus.UnixFD, *dbus.Error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return -1, dbus.MakeFailedError(err)
+	}
+	w.Close()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inhibitors = append(l.inhibitors, r)
+	return dbus.UnixFD(r.Fd()), nil
+}
+
+func (l *fakeLogind) inhibitorCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.inhibitors)
+}
+
+func (l *fakeLogind) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, inhibitor := range l.inhibitors {
+		inhibitor.Close()
+	}
+}
+
+// connectPrivateBus opens a connection to the private bus, closed when the
+// test ends
+func connectPrivateBus(t *testing.T, address string) *dbus.Conn {
+	t.Helper()
+
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connect to private bus: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// startFakeLogind serves fakeLogind under the logind name of a private bus
+func startFakeLogind(t *testing.T, address string) (*dbus.Conn, *fakeLogind) {
+	t.Helper()
+
+	conn := connectPrivateBus(t, address)
+	logind := &fakeLogind{}
+	t.Cleanup(logind.close)
+	if err := conn.Export(logind, logindPath, logindManager); err != nil {
+		t.Fatalf("export logind: %v", err)
+	}
+	reply, err := conn.RequestName(logindService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request logind name: %v (reply %d)", err, reply)
+	}
+	return conn, logind
+}
+
+func TestLogindWatchSession(t *testing.T) {
+	address := startPrivateBus(t)
+	server, logind := startFakeLogind(t, address)
+
+	session, err := newLogindSessionConn(connectPrivateBus(t, address))
+	if err != nil {
+		t.Fatalf("newLogindSessionConn: %v", err)
+	}
+	if session.path != testSessionPath {
+		t.Fatalf("session path = %s, want %s", session.path, testSessionPath)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := session.WatchSession(ctx)
+	if err != nil {
+		t.Fatalf("WatchSession: %v", err)
+	}
+	if got := logind.inhibitorCount(); got != 1 {
+		t.Fatalf("took %d sleep inhibitors, want 1", got)
+	}
+
+	next := func() SessionEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no session event")
+			return SessionEvent{}
+		}
+	}
+	emit := func(path dbus.ObjectPath, name string, body ...interface{}) {
+		t.Helper()
+		if err := server.Emit(path, name, body...); err != nil {
+			t.Fatalf("emit %s: %v", name, err)
+		}
+	}
+
+	emit(logindPath, logindManager+".PrepareForSleep", true)
+	event := next()
+	if event.Kind != SessionSleep {
+		t.Fatalf("event = %s, want %s", event.Kind, SessionSleep)
+	}
+	if event.Release == nil {
+		t.Fatal("sleep event holds no inhibitor to release")
+	}
+	event.Release()
+
+	emit(logindPath, logindManager+".PrepareForSleep", false)
+	if event := next(); event.Kind != SessionWake {
+		t.Fatalf("event = %s, want %s", event.Kind, SessionWake)
+	}
+
+	// Signals of other sessions are ignored
+	emit("/org/freedesktop/login1/session/_32", logindSessionIf+".Lock")
+	emit(testSessionPath, logindSessionIf+".Lock")
+	if event := next(); event.Kind != SessionLock {
+		t.Fatalf("event = %s, want %s", event.Kind, SessionLock)
+	}
+
+	emit(testSessionPath, "org.freedesktop.DBus.Properties.PropertiesChanged",
+		logindSessionIf, map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(false)}, []string{})
+	if event := next(); event.Kind != SessionUnlock {
+		t.Fatalf("event = %s, want %s", event.Kind, SessionUnlock)
+	}
+
+	// The inhibitor released for suspend is taken again on wake
+	if got := logind.inhibitorCount(); got != 2 {
+		t.Errorf("took %d sleep inhibitors, want 2", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("got an event after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("events channel not closed after cancel")
+	}
+}
+
+func TestLogindSessionEvent(t *testing.T) {
+	session := &logindSession{path: testSessionPath}
+
+	tests := []struct {
+		name   string
+		signal *dbus.Signal
+		want   string // "" when the signal is ignored
+	}{
+		{"sleep", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep", Body: []interface{}{true}}, SessionSleep},
+		{"wake", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep", Body: []interface{}{false}}, SessionWake},
+		{"sleep without body", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep"}, ""},
+		{"lock", &dbus.Signal{Path: testSessionPath, Name: logindSessionIf + ".Lock"}, SessionLock},
+		{"unlock", &dbus.Signal{Path: testSessionPath, Name: logindSessionIf + ".Unlock"}, SessionUnlock},
+		{"lock of another session", &dbus.Signal{Path: "/org/freedesktop/login1/session/_32", Name: logindSessionIf + ".Lock"}, ""},
+		{"locked hint", &dbus.Signal{Path: testSessionPath, Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+			Body: []interface{}{logindSessionIf, map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(true)}, []string{}}}, SessionLock},
+		{"other property", &dbus.Signal{Path: testSessionPath, Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+			Body: []interface{}{logindSessionIf, map[string]dbus.Variant{"IdleHint": dbus.MakeVariant(true)}, []string{}}}, ""},
+		{"unrelated signal", &dbus.Signal{Path: testSessionPath, Name: logindSessionIf + ".PauseDevice"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := session.sessionEvent(tt.signal)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("got %s event, want none", event.Kind)
+				}
+				return
+			}
+			if !ok || event.Kind != tt.want {
+				t.Errorf("got %q (ok %v), want %s", event.Kind, ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package capture
+
+import (
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// MonitorProvider is implemented by window managers that can report the
+// monitor layout
+type MonitorProvider interface {
+	GetMonitors() ([]types.Monitor, error)
+}
+
+// assignMonitors sets each window's Monitor to the index of the monitor
+// holding most of its area, or to NoMonitor when it overlaps none
+func assignMonitors(windows []types.Window, monitors []types.Monitor) {
+	for i := range windows {
+		best, bestArea := types.NoMonitor, 0
+		for _, monitor := range monitors {
+			if area := intersectionArea(windows[i].Position, monitor.Bounds); area > bestArea {
+				best, bestArea = monitor.Index, area
+			}
+		}
+		windows[i].Monitor = best
+	}
+}
+
+// monitorName returns the name of the monitor with the given index
+func monitorName(monitors []types.Monitor, index int) string {
+	for _, monitor := range monitors {
+		if monitor.Index == index {
+			return monitor.Name
+		}
+	}
+	return ""
+}
+
+// intersectionArea returns the area two rectangles have in common
+func intersectionArea(a, b types.Rectangle) int {
+	width := min(a.X+a.Width, b.X+b.Width) - max(a.X, b.X)
+	height := min(a.Y+a.Height, b.Y+b.Height) - max(a.Y, b.Y)
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	return width * height
+}
+
+// covers reports whether rectangle a contains all of b
+func covers(a, b types.Rectangle) bool {
+	return a.X <= b.X && a.Y <= b.Y &&
+		a.X+a.Width >= b.X+b.Width && a.Y+a.Height >= b.Y+b.Height
+}
@@ -0,0 +1,165 @@
+package capture
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// pauseRequestTimeout bounds how long Pause and Resume wait for the capture
+// loop, which is busy for at most one capture
+const pauseRequestTimeout = 5 * time.Second
+
+// pauseRequest asks the capture loop to pause for duration, zero meaning
+// until resumed, or to resume
+type pauseRequest struct {
+	resume   bool
+	duration time.Duration
+	reply    chan types.PauseState
+}
+
+// Pause stops tracking for d, or until Resume when d is zero. Pausing while
+// paused changes when the pause ends. The time is recorded as Paused.
+func (c *CaptureEngine) Pause(d time.Duration) (types.PauseState, error) {
+	if d < 0 {
+		return types.PauseState{}, fmt.Errorf("pause duration cannot be negative")
+	}
+	return c.requestPause(pauseRequest{duration: d})
+}
+
+// Resume restarts tracking after Pause
+func (c *CaptureEngine) Resume() (types.PauseState, error) {
+	return c.requestPause(pauseRequest{resume: true})
+}
+
+// PauseState returns whether tracking is paused
+func (c *CaptureEngine) PauseState() types.PauseState {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+	return c.pauseState
+}
+
+// PauseChanges delivers the pause state whenever tracking is paused or
+// resumed, including when a timed pause runs out. Changes are dropped while
+// the receiver is behind.
+func (c *CaptureEngine) PauseChanges() <-chan types.PauseState {
+	return c.pauseChanges
+}
+
+// requestPause hands a request to the capture loop and waits for the result
+func (c *CaptureEngine) requestPause(request pauseRequest) (types.PauseState, error) {
+	request.reply = make(chan types.PauseState, 1)
+
+	select {
+	case c.pauseRequests <- request:
+	case <-time.After(pauseRequestTimeout):
+		return types.PauseState{}, fmt.Errorf("capture engine is not running")
+	}
+	return <-request.reply, nil
+}
+
+// handlePauseRequest pauses or resumes tracking on the capture loop
+func (c *CaptureEngine) handlePauseRequest(request pauseRequest) {
+	now := c.clock.Now()
+
+	switch {
+	case request.resume && c.paused:
+		c.changeAway(now, "Tracking resumed", func() {
+			c.paused = false
+			c.pausedUntil = time.Time{}
+		})
+		c.setPauseState(types.PauseState{})
+	case !request.resume:
+		var until time.Time
+		if request.duration > 0 {
+			until = now.Add(request.duration)
+		}
+
+		since := now
+		if c.paused {
+			if started := c.PauseState().Since; started != nil {
+				since = *started
+			}
+			c.pausedUntil = until
+		} else {
+			c.changeAway(now, "Tracking paused", func() {
+				c.paused = true
+				c.pausedUntil = until
+			})
+		}
+		c.setPauseState(pauseState(since, until))
+	}
+
+	request.reply <- c.PauseState()
+}
+
+// checkPauseExpiry resumes tracking when a timed pause has run out by now
+func (c *CaptureEngine) checkPauseExpiry(now time.Time) {
+	if !c.paused || c.pausedUntil.IsZero() || now.Before(c.pausedUntil) {
+		return
+	}
+
+	c.changeAway(c.pausedUntil, "Pause ended", func() {
+		c.paused = false
+		c.pausedUntil = time.Time{}
+	})
+	c.setPauseState(types.PauseState{})
+}
+
+// restorePause continues a pause stored before the tracker was restarted.
+// Time the tracker was not running is not recorded, so the away period
+// starts now; a pause that ran out in the meantime is cleared.
+func (c *CaptureEngine) restorePause() {
+	state, err := c.storage.GetPauseState()
+	if err != nil {
+		log.Printf("Failed to read pause state: %v", err)
+		return
+	}
+	if !state.Paused {
+		return
+	}
+
+	now := c.clock.Now()
+	if state.Until != nil && !now.Before(*state.Until) {
+		c.setPauseState(types.PauseState{})
+		return
+	}
+
+	log.Printf("Tracking is paused, run 'compass resume' to resume")
+	c.paused = true
+	c.awaySince = now
+	if state.Until != nil {
+		c.pausedUntil = *state.Until
+	}
+	c.pauseMu.Lock()
+	c.pauseState = state
+	c.pauseMu.Unlock()
+}
+
+// setPauseState stores and publishes a new pause state
+func (c *CaptureEngine) setPauseState(state types.PauseState) {
+	c.pauseMu.Lock()
+	c.pauseState = state
+	c.pauseMu.Unlock()
+
+	if err := c.storage.SavePauseState(state); err != nil {
+		log.Printf("Failed to save pause state: %v", err)
+	}
+
+	select {
+	case c.pauseChanges <- state:
+	default:
+	}
+}
+
+// pauseState describes a pause from since until until, zero meaning until
+// resumed
+func pauseState(since, until time.Time) types.PauseState {
+	state := types.PauseState{Paused: true, Since: &since}
+	if !until.IsZero() {
+		state.Until = &until
+	}
+	return state
+}
@@ -104,25 +104,26 @@ func (m *DarwinWindowManager) GetAllWindows() ([]*types.Window, error) {
 	return m.parseAllWindowsInfo(strings.TrimSpace(string(output)))
 }
 
-// TakeScreenshot captures a screenshot (basic implementation)
+// TakeScreenshot captures a screenshot with screencapture into a private
+// temporary file
 func (m *DarwinWindowManager) TakeScreenshot() ([]byte, error) {
-	// Use macOS screencapture command
-	tmpFile := "/tmp/compass_screenshot.png"
-	cmd := exec.Command("screencapture", "-x", tmpFile)
-	err := cmd.Run()
+	tmpFile, err := os.CreateTemp("", "compass-screenshot-*.png")
 	if err != nil {
+		return nil, fmt.Errorf("failed to create screenshot file: %w", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	// Use macOS screencapture command
+	cmd := exec.Command("screencapture", "-x", "-t", "png", tmpFile.Name())
+	if err := cmd.Run(); err != nil {
 		return nil, fmt.Errorf("failed to take screenshot: %w", err)
 	}
 
-	// Read the file
-	data, err := os.ReadFile(tmpFile)
+	data, err := os.ReadFile(tmpFile.Name())
 	if err != nil {
 		return nil, fmt.Errorf("failed to read screenshot: %w", err)
 	}
-
-	// Clean up temp file
-	os.Remove(tmpFile)
-
 	return data, nil
 }
 
@@ -233,6 +234,41 @@ func (m *DarwinWindowManager) GetFocusDuration() time.Duration {
 }
 
 // newPlatformWindowManager creates a platform-specific window manager (macOS)
-func newPlatformWindowManager() types.WindowManager {
+func newPlatformWindowManager(config *types.TrackingConfig) types.WindowManager {
 	return NewDarwinWindowManager()
 }
+
+// newPlatformIdleDetector returns the fallback idle detector (macOS)
+func newPlatformIdleDetector() IdleDetector {
+	return nil
+}
+
+// newPlatformSessionWatcher returns the sleep and lock event source (macOS)
+func newPlatformSessionWatcher() SessionWatcher {
+	return nil
+}
+
+// readProcessInfo is not implemented on macOS
+func readProcessInfo(pid int) (*types.ProcessInfo, error) {
+	return nil, fmt.Errorf("process details are not supported on macOS")
+}
+
+// readProcessTree is not implemented on macOS
+func readProcessTree() processTree {
+	return nil
+}
+
+// foregroundCommand is not implemented on macOS
+func foregroundCommand(tree processTree, pid int) []string {
+	return nil
+}
+
+// readProcessUsages is not implemented on macOS
+func readProcessUsages(pids []int, tree processTree) map[int]*processUsage {
+	return nil
+}
+
+// readProcessConnections is not implemented on macOS
+func readProcessConnections(pids []int, tree processTree) map[int][]tcpEndpoint {
+	return nil
+}
@@ -0,0 +1,249 @@
+//go:build linux
+
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// hyprlandClient is a window as reported by `hyprctl -j clients`
+type hyprlandClient struct {
+	Address string `json:"address"`
+	Mapped  bool   `json:"mapped"`
+	Hidden  bool   `json:"hidden"`
+	At      [2]int `json:"at"`
+	Size    [2]int `json:"size"`
+	Monitor int    `json:"monitor"`
+	Class   string `json:"class"`
+	Title   string `json:"title"`
+	PID     int    `json:"pid"`
+}
+
+// hyprlandMonitor is a monitor as reported by `hyprctl -j monitors`
+type hyprlandMonitor struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Scale   float64 `json:"scale"`
+	Focused bool    `json:"focused"`
+}
+
+// HyprlandWindowManager implements WindowManager using Hyprland's IPC sockets
+type HyprlandWindowManager struct {
+	socketDir string
+	focus     *focusTracker
+}
+
+// NewHyprlandWindowManager locates the sockets of the Hyprland instance
+// named by $HYPRLAND_INSTANCE_SIGNATURE
+func NewHyprlandWindowManager() (*HyprlandWindowManager, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" {
+		return nil, fmt.Errorf("HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+
+	// Hyprland 0.40+ keeps its sockets under $XDG_RUNTIME_DIR, older
+	// releases under /tmp
+	candidates := []string{filepath.Join("/tmp", "hypr", signature)}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		candidates = append([]string{filepath.Join(runtimeDir, "hypr", signature)}, candidates...)
+	}
+
+	for _, dir := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, ".socket.sock")); err == nil {
+			return &HyprlandWindowManager{
+				socketDir: dir,
+				focus:     newFocusTracker(),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("hyprland socket not found for instance %s", signature)
+}
+
+// GetActiveWindow gets the currently focused window
+func (m *HyprlandWindowManager) GetActiveWindow() (*types.Window, error) {
+	var active hyprlandClient
+	if err := m.request("j/activewindow", &active); err != nil {
+		return nil, fmt.Errorf("failed to get active window: %w", err)
+	}
+	if active.Address == "" {
+		return nil, fmt.Errorf("no active window found")
+	}
+
+	window := active.toWindow(true)
+	m.focus.observe(window)
+	return window, nil
+}
+
+// GetAllWindows gets all mapped windows
+func (m *HyprlandWindowManager) GetAllWindows() ([]*types.Window, error) {
+	var clients []hyprlandClient
+	if err := m.request("j/clients", &clients); err != nil {
+		return nil, fmt.Errorf("failed to get window list: %w", err)
+	}
+
+	var active hyprlandClient
+	m.request("j/activewindow", &active)
+
+	windows := make([]*types.Window, 0, len(clients))
+	for _, client := range clients {
+		if !client.Mapped || client.Hidden {
+			continue
+		}
+
+		window := client.toWindow(active.Address != "" && client.Address == active.Address)
+		if window.IsActive {
+			m.focus.observe(window)
+		}
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+// TakeScreenshot captures a screenshot of all monitors
+func (m *HyprlandWindowManager) TakeScreenshot() ([]byte, error) {
+	return grimScreenshot()
+}
+
+// GetFocusDuration returns how long the current window has been in focus
+func (m *HyprlandWindowManager) GetFocusDuration() time.Duration {
+	return m.focus.duration()
+}
+
+// GetMonitors returns the monitor layout in logical (scaled) coordinates,
+// which is the coordinate space of client positions. Hyprland has no primary
+// monitor, so the focused one is reported as primary.
+func (m *HyprlandWindowManager) GetMonitors() ([]types.Monitor, error) {
+	var reply []hyprlandMonitor
+	if err := m.request("j/monitors", &reply); err != nil {
+		return nil, fmt.Errorf("failed to get monitors: %w", err)
+	}
+
+	monitors := make([]types.Monitor, 0, len(reply))
+	for i, monitor := range reply {
+		scale := monitor.Scale
+		if scale <= 0 {
+			scale = 1
+		}
+		monitors = append(monitors, types.Monitor{
+			Index: i,
+			Name:  monitor.Name,
+			Bounds: types.Rectangle{
+				X:      monitor.X,
+				Y:      monitor.Y,
+				Width:  int(float64(monitor.Width) / scale),
+				Height: int(float64(monitor.Height) / scale),
+			},
+			Primary: monitor.Focused,
+		})
+	}
+	return monitors, nil
+}
+
+// WatchFocus listens on the event socket (.socket2.sock) for focus and title changes
+func (m *HyprlandWindowManager) WatchFocus(ctx context.Context) (<-chan FocusEvent, error) {
+	conn, err := net.Dial("unix", filepath.Join(m.socketDir, ".socket2.sock"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to hyprland event socket: %w", err)
+	}
+
+	var active hyprlandClient
+	m.request("j/activewindow", &active)
+	activeAddress := strings.TrimPrefix(active.Address, "0x")
+
+	events := make(chan FocusEvent, 16)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		defer close(events)
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			// Events are "NAME>>DATA" lines
+			name, data, found := strings.Cut(scanner.Text(), ">>")
+			if !found {
+				continue
+			}
+
+			switch name {
+			case "activewindowv2":
+				activeAddress = strings.TrimPrefix(data, "0x")
+				sendFocusEvent(events, FocusEvent{Timestamp: time.Now()})
+			case "windowtitle":
+				if strings.TrimPrefix(data, "0x") == activeAddress {
+					sendFocusEvent(events, FocusEvent{Timestamp: time.Now()})
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// request sends a command to the request socket (.socket.sock) and decodes
+// the JSON reply; Hyprland closes the connection after each reply
+func (m *HyprlandWindowManager) request(command string, reply interface{}) error {
+	conn, err := net.Dial("unix", filepath.Join(m.socketDir, ".socket.sock"))
+	if err != nil {
+		return fmt.Errorf("failed to connect to hyprland: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(command)); err != nil {
+		return fmt.Errorf("failed to send %s: %w", command, err)
+	}
+
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read %s reply: %w", command, err)
+	}
+
+	if err := json.Unmarshal(data, reply); err != nil {
+		return fmt.Errorf("failed to parse %s reply: %w", command, err)
+	}
+	return nil
+}
+
+// toWindow converts a Hyprland client to a Window
+func (c *hyprlandClient) toWindow(isActive bool) *types.Window {
+	appName := c.Class
+	if appName == "" {
+		appName = "Unknown"
+	}
+
+	return &types.Window{
+		AppName:    appName,
+		Title:      c.Title,
+		ProcessID:  c.PID,
+		IsActive:   isActive,
+		LastActive: time.Now(),
+		Position: types.Rectangle{
+			X:      c.At[0],
+			Y:      c.At[1],
+			Width:  c.Size[0],
+			Height: c.Size[1],
+		},
+		Monitor: 0,
+	}
+}
@@ -0,0 +1,210 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+const hyprlandTestClients = `[
+	{"address": "0xa1", "mapped": true, "at": [0, 0], "size": [1280, 1440], "monitor": 0,
+		"class": "kitty", "title": "vim", "pid": 10},
+	{"address": "0xa2", "mapped": true, "at": [1280, 0], "size": [1280, 1440], "monitor": 0,
+		"class": "", "title": "untitled", "pid": 20},
+	{"address": "0xa3", "mapped": true, "hidden": true, "class": "firefox", "title": "tab", "pid": 30},
+	{"address": "0xa4", "mapped": false, "class": "popup", "pid": 40}
+]`
+
+const hyprlandTestMonitors = `[
+	{"id": 0, "name": "DP-1", "width": 2560, "height": 1440, "x": 0, "y": 0, "scale": 1, "focused": true},
+	{"id": 1, "name": "eDP-1", "width": 2880, "height": 1800, "x": 2560, "y": 0, "scale": 2}
+]`
+
+// fakeHyprland serves the request and event sockets of a Hyprland instance
+// in a temporary $XDG_RUNTIME_DIR. Requests get the canned reply for their
+// command; event lines are written to every event socket connection.
+type fakeHyprland struct {
+	replies map[string]string
+	events  []string
+}
+
+func newFakeHyprland(t *testing.T, replies map[string]string, events []string) {
+	t.Helper()
+
+	runtimeDir := t.TempDir()
+	dir := filepath.Join(runtimeDir, "hypr", "test")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("HYPRLAND_INSTANCE_SIGNATURE", "test")
+
+	server := &fakeHyprland{replies: replies, events: events}
+	server.listen(t, filepath.Join(dir, ".socket.sock"), server.handleRequest)
+	server.listen(t, filepath.Join(dir, ".socket2.sock"), server.handleEvents)
+}
+
+func (s *fakeHyprland) listen(t *testing.T, path string, handle func(net.Conn)) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+}
+
+func (s *fakeHyprland) handleRequest(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+	conn.Write([]byte(s.replies[string(buf[:n])]))
+}
+
+func (s *fakeHyprland) handleEvents(conn net.Conn) {
+	conn.Write([]byte(strings.Join(s.events, "\n") + "\n"))
+	// Held open like Hyprland's event socket; closed by the client
+	buf := make([]byte, 1)
+	conn.Read(buf)
+	conn.Close()
+}
+
+func TestHyprlandGetAllWindows(t *testing.T) {
+	newFakeHyprland(t, map[string]string{
+		"j/clients":      hyprlandTestClients,
+		"j/activewindow": `{"address": "0xa1", "class": "kitty", "title": "vim", "pid": 10}`,
+	}, nil)
+
+	m, err := NewHyprlandWindowManager()
+	if err != nil {
+		t.Fatalf("NewHyprlandWindowManager: %v", err)
+	}
+
+	windows, err := m.GetAllWindows()
+	if err != nil {
+		t.Fatalf("GetAllWindows: %v", err)
+	}
+
+	want := []types.Window{
+		{AppName: "kitty", Title: "vim", ProcessID: 10, IsActive: true,
+			Position: types.Rectangle{Width: 1280, Height: 1440}},
+		{AppName: "Unknown", Title: "untitled", ProcessID: 20,
+			Position: types.Rectangle{X: 1280, Width: 1280, Height: 1440}},
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("got %d windows, want %d (hidden and unmapped clients are skipped)", len(windows), len(want))
+	}
+	for i, w := range windows {
+		if w.AppName != want[i].AppName || w.Title != want[i].Title || w.ProcessID != want[i].ProcessID ||
+			w.IsActive != want[i].IsActive || w.Position != want[i].Position {
+			t.Errorf("window %d = %+v, want %+v", i, *w, want[i])
+		}
+	}
+}
+
+func TestHyprlandGetActiveWindow(t *testing.T) {
+	newFakeHyprland(t, map[string]string{"j/activewindow": `{}`}, nil)
+
+	m, err := NewHyprlandWindowManager()
+	if err != nil {
+		t.Fatalf("NewHyprlandWindowManager: %v", err)
+	}
+
+	// An empty reply means no window has focus, e.g. on an empty workspace
+	if _, err := m.GetActiveWindow(); err == nil {
+		t.Error("GetActiveWindow succeeded without an active window")
+	}
+}
+
+func TestHyprlandGetMonitors(t *testing.T) {
+	newFakeHyprland(t, map[string]string{"j/monitors": hyprlandTestMonitors}, nil)
+
+	m, err := NewHyprlandWindowManager()
+	if err != nil {
+		t.Fatalf("NewHyprlandWindowManager: %v", err)
+	}
+
+	monitors, err := m.GetMonitors()
+	if err != nil {
+		t.Fatalf("GetMonitors: %v", err)
+	}
+
+	// Bounds are in logical pixels, so the scaled monitor is half its mode
+	want := []types.Monitor{
+		{Index: 0, Name: "DP-1", Bounds: types.Rectangle{Width: 2560, Height: 1440}, Primary: true},
+		{Index: 1, Name: "eDP-1", Bounds: types.Rectangle{X: 2560, Width: 1440, Height: 900}},
+	}
+	if len(monitors) != len(want) {
+		t.Fatalf("got %d monitors, want %d", len(monitors), len(want))
+	}
+	for i := range want {
+		if monitors[i] != want[i] {
+			t.Errorf("monitor %d = %+v, want %+v", i, monitors[i], want[i])
+		}
+	}
+}
+
+func TestHyprlandWatchFocus(t *testing.T) {
+	newFakeHyprland(t, map[string]string{
+		"j/activewindow": `{"address": "0xa1"}`,
+	}, []string{
+		"windowtitle>>a2",
+		"workspace>>2",
+		"windowtitle>>a1",
+		"activewindowv2>>a2",
+		"windowtitle>>a2",
+	})
+
+	m, err := NewHyprlandWindowManager()
+	if err != nil {
+		t.Fatalf("NewHyprlandWindowManager: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := m.WatchFocus(ctx)
+	if err != nil {
+		t.Fatalf("WatchFocus: %v", err)
+	}
+
+	// Title changes count only for the focused window, which follows
+	// activewindowv2
+	for i := 0; i < 3; i++ {
+		select {
+		case <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("got %d focus events, want 3", i)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("got a fourth focus event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("events channel not closed after cancel")
+	}
+}
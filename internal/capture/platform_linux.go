@@ -4,6 +4,7 @@ package capture
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"strconv"
@@ -154,24 +155,16 @@ func (m *LinuxWindowManager) GetAllWindows() ([]*types.Window, error) {
 
 // TakeScreenshot captures a screenshot using ImageMagick import
 func (m *LinuxWindowManager) TakeScreenshot() ([]byte, error) {
-	tmpFile := "/tmp/compass_screenshot.png"
+	return importScreenshot()
+}
 
-	// Use ImageMagick import command (available on the system)
-	cmd := exec.Command("import", "-window", "root", tmpFile)
-	err := cmd.Run()
+// importScreenshot captures the root window as PNG using ImageMagick import,
+// which writes to stdout so concurrent captures cannot clobber each other
+func importScreenshot() ([]byte, error) {
+	data, err := exec.Command("import", "-window", "root", "png:-").Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to take screenshot: %w", err)
 	}
-
-	// Read the file
-	data, err := os.ReadFile(tmpFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read screenshot: %w", err)
-	}
-
-	// Clean up temp file
-	os.Remove(tmpFile)
-
 	return data, nil
 }
 
@@ -244,7 +237,7 @@ func (m *LinuxWindowManager) getWindowInfo(windowID string, isActive bool) (*typ
 		IsActive:   isActive,
 		LastActive: time.Now(),
 		Position:   rect,
-		Monitor:    0, // TODO: Add multi-monitor support
+		Monitor:    0, // Assigned by the engine from the monitor layout
 	}
 
 	return window, nil
@@ -297,32 +290,52 @@ func (m *LinuxWindowManager) getWindowGeometry(windowID string) (types.Rectangle
 	return rect, nil
 }
 
-// GetFocusDuration returns how long the current window has been in focus
-func (m *LinuxWindowManager) GetFocusDuration() time.Duration {
-	if m.lastActiveWindow == nil {
-		return 0
+// GetMonitors gets the monitor layout using xrandr
+func (m *LinuxWindowManager) GetMonitors() ([]types.Monitor, error) {
+	cmd := exec.Command("xrandr", "--listmonitors")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitors: %w", err)
 	}
 
-	// Calculate duration for current focus session
-	duration := time.Since(m.focusStartTime)
+	// Lines look like " 0: +*eDP-1 1920/344x1080/194+0+0  eDP-1", where "*"
+	// marks the primary monitor
+	monitors := make([]types.Monitor, 0)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
 
-	// Cap focus duration to prevent unrealistic values
-	// Maximum of 6 hours per session (reasonable for deep work)
-	maxDuration := 6 * time.Hour
-	if duration > maxDuration {
-		// Reset the timer to prevent further accumulation
-		m.focusStartTime = time.Now()
-		return maxDuration
+		index, err := strconv.Atoi(strings.TrimSuffix(fields[0], ":"))
+		if err != nil {
+			continue
+		}
+
+		var width, height, x, y, widthMM, heightMM int
+		if _, err := fmt.Sscanf(fields[2], "%d/%dx%d/%d+%d+%d", &width, &widthMM, &height, &heightMM, &x, &y); err != nil {
+			continue
+		}
+
+		name := strings.TrimLeft(fields[1], "+*")
+		monitors = append(monitors, types.Monitor{
+			Index:   index,
+			Name:    name,
+			Bounds:  types.Rectangle{X: x, Y: y, Width: width, Height: height},
+			Primary: strings.Contains(fields[1], "*"),
+		})
 	}
 
-	// Additional safeguard: if duration is unreasonably long (>12 hours),
-	// this indicates a bug or system sleep/resume, so reset
-	if duration > 12*time.Hour {
-		m.focusStartTime = time.Now()
+	return monitors, nil
+}
+
+// GetFocusDuration returns how long the current window has been in focus.
+// Time spent away from the keyboard is handled by the engine's idle detection.
+func (m *LinuxWindowManager) GetFocusDuration() time.Duration {
+	if m.lastActiveWindow == nil {
 		return 0
 	}
-
-	return duration
+	return time.Since(m.focusStartTime)
 }
 
 // ResetFocusTracking resets focus tracking for all applications
@@ -333,7 +346,42 @@ func (m *LinuxWindowManager) ResetFocusTracking() {
 	m.focusSessions = make(map[string]time.Time)
 }
 
-// newPlatformWindowManager creates a platform-specific window manager (Linux)
-func newPlatformWindowManager() types.WindowManager {
-	return NewLinuxWindowManager()
+// newPlatformWindowManager creates a platform-specific window manager (Linux).
+// With the auto backend, Wayland sessions use the compositor's IPC (sway,
+// Hyprland) and X11 sessions the native X11 protocol. The xprop/wmctrl based
+// manager is used when configured explicitly or as the last resort.
+func newPlatformWindowManager(config *types.TrackingConfig) types.WindowManager {
+	backend := config.Backend
+	if backend == types.BackendAuto {
+		backend = detectLinuxBackend()
+	}
+
+	switch backend {
+	case types.BackendExec:
+		return NewLinuxWindowManager()
+	case types.BackendSway:
+		windowMgr, err := NewSwayWindowManager()
+		if err == nil {
+			return windowMgr
+		}
+		log.Printf("Sway backend unavailable, falling back to X11: %v", err)
+	case types.BackendHyprland:
+		windowMgr, err := NewHyprlandWindowManager()
+		if err == nil {
+			return windowMgr
+		}
+		log.Printf("Hyprland backend unavailable, falling back to X11: %v", err)
+	}
+
+	if os.Getenv("XDG_SESSION_TYPE") == "wayland" {
+		log.Printf("Wayland session without a supported compositor IPC, only XWayland windows will be visible")
+	}
+
+	windowMgr, err := NewX11WindowManager()
+	if err != nil {
+		log.Printf("Native X11 backend unavailable, falling back to xprop/wmctrl: %v", err)
+		return NewLinuxWindowManager()
+	}
+
+	return windowMgr
 }
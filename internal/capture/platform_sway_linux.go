@@ -0,0 +1,335 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// sway IPC message types, see sway-ipc(7)
+const (
+	swaySubscribe   uint32 = 2
+	swayGetOutputs  uint32 = 3
+	swayGetTree     uint32 = 4
+	swayEventWindow uint32 = 0x80000003
+)
+
+// swayMagic prefixes every sway IPC message
+const swayMagic = "i3-ipc"
+
+// swayNode is a node of the sway layout tree returned by GET_TREE
+type swayNode struct {
+	ID               int64  `json:"id"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Focused          bool   `json:"focused"`
+	PID              int    `json:"pid"`
+	AppID            string `json:"app_id"`
+	WindowProperties *struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+	Rect          types.Rectangle `json:"rect"`
+	Nodes         []swayNode      `json:"nodes"`
+	FloatingNodes []swayNode      `json:"floating_nodes"`
+}
+
+// swayOutput is an output as returned by GET_OUTPUTS
+type swayOutput struct {
+	Name    string          `json:"name"`
+	Active  bool            `json:"active"`
+	Primary bool            `json:"primary"`
+	Focused bool            `json:"focused"`
+	Rect    types.Rectangle `json:"rect"`
+}
+
+// swayWindowEvent is the payload of a sway window event
+type swayWindowEvent struct {
+	Change    string   `json:"change"`
+	Container swayNode `json:"container"`
+}
+
+// SwayWindowManager implements WindowManager using the sway IPC socket
+type SwayWindowManager struct {
+	socketPath string
+	focus      *focusTracker
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSwayWindowManager connects to the sway IPC socket named by $SWAYSOCK
+func NewSwayWindowManager() (*SwayWindowManager, error) {
+	socketPath := os.Getenv("SWAYSOCK")
+	if socketPath == "" {
+		return nil, fmt.Errorf("SWAYSOCK is not set")
+	}
+
+	m := &SwayWindowManager{
+		socketPath: socketPath,
+		focus:      newFocusTracker(),
+	}
+
+	if _, err := m.getTree(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Close closes the IPC connection
+func (m *SwayWindowManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.conn != nil {
+		err := m.conn.Close()
+		m.conn = nil
+		return err
+	}
+	return nil
+}
+
+// GetActiveWindow gets the currently focused window
+func (m *SwayWindowManager) GetActiveWindow() (*types.Window, error) {
+	windows, err := m.GetAllWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range windows {
+		if w.IsActive {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("no active window found")
+}
+
+// GetAllWindows gets all windows from the sway layout tree
+func (m *SwayWindowManager) GetAllWindows() ([]*types.Window, error) {
+	tree, err := m.getTree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get window list: %w", err)
+	}
+
+	windows := make([]*types.Window, 0)
+	collectSwayWindows(tree, &windows)
+
+	for _, w := range windows {
+		if w.IsActive {
+			m.focus.observe(w)
+		}
+	}
+
+	return windows, nil
+}
+
+// TakeScreenshot captures a screenshot of all outputs
+func (m *SwayWindowManager) TakeScreenshot() ([]byte, error) {
+	return grimScreenshot()
+}
+
+// GetFocusDuration returns how long the current window has been in focus
+func (m *SwayWindowManager) GetFocusDuration() time.Duration {
+	return m.focus.duration()
+}
+
+// GetMonitors returns the active outputs. Sway has no primary output, so
+// the focused one is reported as primary.
+func (m *SwayWindowManager) GetMonitors() ([]types.Monitor, error) {
+	payload, err := m.request(swayGetOutputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outputs: %w", err)
+	}
+
+	var outputs []swayOutput
+	if err := json.Unmarshal(payload, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse outputs: %w", err)
+	}
+
+	monitors := make([]types.Monitor, 0, len(outputs))
+	for _, output := range outputs {
+		if !output.Active {
+			continue
+		}
+		monitors = append(monitors, types.Monitor{
+			Index:   len(monitors),
+			Name:    output.Name,
+			Bounds:  output.Rect,
+			Primary: output.Primary || output.Focused,
+		})
+	}
+	return monitors, nil
+}
+
+// WatchFocus subscribes to sway window events on a dedicated connection
+func (m *SwayWindowManager) WatchFocus(ctx context.Context) (<-chan FocusEvent, error) {
+	conn, err := net.Dial("unix", m.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sway: %w", err)
+	}
+
+	if _, err := swayRequest(conn, swaySubscribe, []byte(`["window"]`)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to window events: %w", err)
+	}
+
+	events := make(chan FocusEvent, 16)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		defer close(events)
+		defer conn.Close()
+
+		for {
+			msgType, payload, err := readSwayMessage(conn)
+			if err != nil {
+				return
+			}
+			if msgType != swayEventWindow {
+				continue
+			}
+
+			var event swayWindowEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				continue
+			}
+
+			if event.Change == "focus" || (event.Change == "title" && event.Container.Focused) {
+				sendFocusEvent(events, FocusEvent{Timestamp: time.Now()})
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// getTree requests the layout tree
+func (m *SwayWindowManager) getTree() (*swayNode, error) {
+	payload, err := m.request(swayGetTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	var tree swayNode
+	if err := json.Unmarshal(payload, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse tree: %w", err)
+	}
+	return &tree, nil
+}
+
+// request sends a payload-less IPC request, reconnecting once if the
+// connection broke
+func (m *SwayWindowManager) request(msgType uint32) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var payload []byte
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if m.conn == nil {
+			if m.conn, err = net.Dial("unix", m.socketPath); err != nil {
+				return nil, fmt.Errorf("failed to connect to sway: %w", err)
+			}
+		}
+
+		if payload, err = swayRequest(m.conn, msgType, nil); err == nil {
+			return payload, nil
+		}
+		m.conn.Close()
+		m.conn = nil
+	}
+	return nil, err
+}
+
+// collectSwayWindows walks the layout tree and appends every view
+func collectSwayWindows(node *swayNode, windows *[]*types.Window) {
+	// The scratchpad lives on the hidden __i3 output
+	if node.Type == "output" && node.Name == "__i3" {
+		return
+	}
+
+	isView := (node.Type == "con" || node.Type == "floating_con") &&
+		len(node.Nodes) == 0 && len(node.FloatingNodes) == 0 && node.PID > 0
+	if isView {
+		appName := node.AppID
+		if appName == "" && node.WindowProperties != nil {
+			// XWayland clients have a WM_CLASS instead of an app_id
+			appName = node.WindowProperties.Class
+		}
+		if appName == "" {
+			appName = "Unknown"
+		}
+
+		*windows = append(*windows, &types.Window{
+			AppName:    appName,
+			Title:      node.Name,
+			ProcessID:  node.PID,
+			IsActive:   node.Focused,
+			LastActive: time.Now(),
+			Position:   node.Rect,
+			Monitor:    0,
+		})
+	}
+
+	for i := range node.Nodes {
+		collectSwayWindows(&node.Nodes[i], windows)
+	}
+	for i := range node.FloatingNodes {
+		collectSwayWindows(&node.FloatingNodes[i], windows)
+	}
+}
+
+// swayRequest sends an IPC message and reads its reply
+func swayRequest(conn net.Conn, msgType uint32, payload []byte) ([]byte, error) {
+	header := make([]byte, len(swayMagic)+8)
+	copy(header, swayMagic)
+	binary.NativeEndian.PutUint32(header[len(swayMagic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(header[len(swayMagic)+4:], msgType)
+
+	if _, err := conn.Write(append(header, payload...)); err != nil {
+		return nil, err
+	}
+
+	replyType, reply, err := readSwayMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if replyType != msgType {
+		return nil, fmt.Errorf("unexpected reply type %d for request %d", replyType, msgType)
+	}
+	return reply, nil
+}
+
+// readSwayMessage reads one IPC message (reply or event) from the socket
+func readSwayMessage(conn net.Conn) (uint32, []byte, error) {
+	header := make([]byte, len(swayMagic)+8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	if string(header[:len(swayMagic)]) != swayMagic {
+		return 0, nil, fmt.Errorf("invalid IPC magic")
+	}
+
+	length := binary.NativeEndian.Uint32(header[len(swayMagic):])
+	msgType := binary.NativeEndian.Uint32(header[len(swayMagic)+4:])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return msgType, payload, nil
+}
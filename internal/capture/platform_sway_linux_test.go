@@ -0,0 +1,239 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+const swayTestTree = `{
+	"id": 1, "type": "root", "nodes": [
+		{"id": 2, "type": "output", "name": "__i3", "nodes": [
+			{"id": 3, "type": "workspace", "name": "__i3_scratch", "floating_nodes": [
+				{"id": 4, "type": "floating_con", "name": "hidden", "pid": 40, "app_id": "scratch"}
+			]}
+		]},
+		{"id": 5, "type": "output", "name": "DP-1", "nodes": [
+			{"id": 6, "type": "workspace", "name": "1", "nodes": [
+				{"id": 7, "type": "con", "name": "main.go - code", "pid": 70, "app_id": "code", "focused": true,
+					"rect": {"x": 0, "y": 0, "width": 960, "height": 1080}},
+				{"id": 8, "type": "con", "name": "Slack", "pid": 80,
+					"window_properties": {"class": "Slack"},
+					"rect": {"x": 960, "y": 0, "width": 960, "height": 1080}}
+			], "floating_nodes": [
+				{"id": 9, "type": "floating_con", "name": "", "pid": 90,
+					"rect": {"x": 100, "y": 100, "width": 400, "height": 300}}
+			]}
+		]}
+	]
+}`
+
+const swayTestOutputs = `[
+	{"name": "DP-1", "active": true, "focused": true, "rect": {"x": 0, "y": 0, "width": 1920, "height": 1080}},
+	{"name": "HDMI-A-1", "active": false, "rect": {"x": 0, "y": 0, "width": 0, "height": 0}},
+	{"name": "DP-2", "active": true, "rect": {"x": 1920, "y": 0, "width": 2560, "height": 1440}}
+]`
+
+// fakeSway serves the sway IPC protocol on a socket in a temporary
+// directory. Each connection is closed after oneShot replies when oneShot is
+// positive; subscriptions are answered and followed by events.
+type fakeSway struct {
+	listener net.Listener
+	oneShot  int
+	events   []string
+}
+
+func newFakeSway(t *testing.T) *fakeSway {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sway.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	t.Setenv("SWAYSOCK", path)
+	return &fakeSway{listener: listener}
+}
+
+func (s *fakeSway) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSway) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for replies := 0; s.oneShot <= 0 || replies < s.oneShot; replies++ {
+		msgType, _, err := readSwayMessage(conn)
+		if err != nil {
+			return
+		}
+
+		switch msgType {
+		case swayGetTree:
+			writeSwayMessage(conn, msgType, swayTestTree)
+		case swayGetOutputs:
+			writeSwayMessage(conn, msgType, swayTestOutputs)
+		case swaySubscribe:
+			writeSwayMessage(conn, msgType, `{"success": true}`)
+			for _, event := range s.events {
+				writeSwayMessage(conn, swayEventWindow, event)
+			}
+		}
+	}
+}
+
+func writeSwayMessage(conn net.Conn, msgType uint32, payload string) {
+	header := make([]byte, len(swayMagic)+8)
+	copy(header, swayMagic)
+	binary.NativeEndian.PutUint32(header[len(swayMagic):], uint32(len(payload)))
+	binary.NativeEndian.PutUint32(header[len(swayMagic)+4:], msgType)
+	conn.Write(append(header, payload...))
+}
+
+func TestSwayGetAllWindows(t *testing.T) {
+	server := newFakeSway(t)
+	go server.serve()
+
+	m, err := NewSwayWindowManager()
+	if err != nil {
+		t.Fatalf("NewSwayWindowManager: %v", err)
+	}
+	defer m.Close()
+
+	windows, err := m.GetAllWindows()
+	if err != nil {
+		t.Fatalf("GetAllWindows: %v", err)
+	}
+
+	want := []types.Window{
+		{AppName: "code", Title: "main.go - code", ProcessID: 70, IsActive: true,
+			Position: types.Rectangle{Width: 960, Height: 1080}},
+		{AppName: "Slack", Title: "Slack", ProcessID: 80,
+			Position: types.Rectangle{X: 960, Width: 960, Height: 1080}},
+		{AppName: "Unknown", ProcessID: 90,
+			Position: types.Rectangle{X: 100, Y: 100, Width: 400, Height: 300}},
+	}
+	if len(windows) != len(want) {
+		t.Fatalf("got %d windows, want %d (scratchpad windows are skipped)", len(windows), len(want))
+	}
+	for i, w := range windows {
+		if w.AppName != want[i].AppName || w.Title != want[i].Title || w.ProcessID != want[i].ProcessID ||
+			w.IsActive != want[i].IsActive || w.Position != want[i].Position {
+			t.Errorf("window %d = %+v, want %+v", i, *w, want[i])
+		}
+	}
+
+	active, err := m.GetActiveWindow()
+	if err != nil {
+		t.Fatalf("GetActiveWindow: %v", err)
+	}
+	if active.AppName != "code" {
+		t.Errorf("active window = %s, want code", active.AppName)
+	}
+}
+
+func TestSwayGetMonitors(t *testing.T) {
+	server := newFakeSway(t)
+	go server.serve()
+
+	m, err := NewSwayWindowManager()
+	if err != nil {
+		t.Fatalf("NewSwayWindowManager: %v", err)
+	}
+	defer m.Close()
+
+	monitors, err := m.GetMonitors()
+	if err != nil {
+		t.Fatalf("GetMonitors: %v", err)
+	}
+
+	want := []types.Monitor{
+		{Index: 0, Name: "DP-1", Bounds: types.Rectangle{Width: 1920, Height: 1080}, Primary: true},
+		{Index: 1, Name: "DP-2", Bounds: types.Rectangle{X: 1920, Width: 2560, Height: 1440}},
+	}
+	if len(monitors) != len(want) {
+		t.Fatalf("got %d monitors, want %d (inactive outputs are skipped)", len(monitors), len(want))
+	}
+	for i := range want {
+		if monitors[i] != want[i] {
+			t.Errorf("monitor %d = %+v, want %+v", i, monitors[i], want[i])
+		}
+	}
+}
+
+func TestSwayReconnects(t *testing.T) {
+	server := newFakeSway(t)
+	server.oneShot = 1
+	go server.serve()
+
+	m, err := NewSwayWindowManager()
+	if err != nil {
+		t.Fatalf("NewSwayWindowManager: %v", err)
+	}
+	defer m.Close()
+
+	// Every request after the first finds the previous connection closed
+	for i := 0; i < 3; i++ {
+		if _, err := m.GetAllWindows(); err != nil {
+			t.Fatalf("GetAllWindows %d: %v", i, err)
+		}
+	}
+}
+
+func TestSwayWatchFocus(t *testing.T) {
+	server := newFakeSway(t)
+	server.events = []string{
+		`{"change": "title", "container": {"focused": false}}`,
+		`{"change": "focus", "container": {"focused": true}}`,
+		`{"change": "title", "container": {"focused": true}}`,
+	}
+	go server.serve()
+
+	m, err := NewSwayWindowManager()
+	if err != nil {
+		t.Fatalf("NewSwayWindowManager: %v", err)
+	}
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := m.WatchFocus(ctx)
+	if err != nil {
+		t.Fatalf("WatchFocus: %v", err)
+	}
+
+	// The title change of an unfocused window is not a focus event
+	for i := 0; i < 2; i++ {
+		select {
+		case <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("got %d focus events, want 2", i)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("got a third focus event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("events channel not closed after cancel")
+	}
+}
@@ -0,0 +1,513 @@
+//go:build linux
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/randr"
+	"github.com/jezek/xgb/screensaver"
+	"github.com/jezek/xgb/xproto"
+)
+
+// maxPropertyLength is the maximum property size requested, in 32-bit units
+const maxPropertyLength = 1 << 16
+
+// x11ImageStripBytes is the size of the screen strips read by CaptureImage
+const x11ImageStripBytes = 4 << 20
+
+// X11WindowManager implements WindowManager by talking the X11 protocol
+// directly over a single connection instead of spawning xprop/wmctrl
+type X11WindowManager struct {
+	conn  *xgb.Conn
+	root  xproto.Window
+	focus *focusTracker
+
+	// hasScreenSaver is set when the MIT-SCREEN-SAVER extension is available
+	hasScreenSaver bool
+	// hasRandrMonitors is set when RandR 1.5 monitors can be queried
+	hasRandrMonitors bool
+
+	atomMu sync.Mutex
+	atoms  map[string]xproto.Atom
+}
+
+// NewX11WindowManager connects to the X server named by $DISPLAY
+func NewX11WindowManager() (*X11WindowManager, error) {
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+
+	m := &X11WindowManager{
+		conn:  conn,
+		root:  xproto.Setup(conn).DefaultScreen(conn).Root,
+		focus: newFocusTracker(),
+		atoms: make(map[string]xproto.Atom),
+	}
+
+	// The EWMH client list is required for window enumeration
+	if _, err := m.windowListProperty(m.root, "_NET_CLIENT_LIST"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("window manager does not support EWMH: %w", err)
+	}
+
+	m.hasScreenSaver = screensaver.Init(conn) == nil
+	if randr.Init(conn) == nil {
+		version, err := randr.QueryVersion(conn, 1, 5).Reply()
+		m.hasRandrMonitors = err == nil &&
+			(version.MajorVersion > 1 || version.MinorVersion >= 5)
+	}
+
+	return m, nil
+}
+
+// Close closes the X server connection
+func (m *X11WindowManager) Close() error {
+	m.conn.Close()
+	return nil
+}
+
+// GetActiveWindow gets the currently active window
+func (m *X11WindowManager) GetActiveWindow() (*types.Window, error) {
+	id, err := m.activeWindowID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active window: %w", err)
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("no active window found")
+	}
+
+	window, err := m.getWindowInfo(id, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get window info: %w", err)
+	}
+
+	m.focus.observe(window)
+	return window, nil
+}
+
+// GetAllWindows gets all managed windows from _NET_CLIENT_LIST
+func (m *X11WindowManager) GetAllWindows() ([]*types.Window, error) {
+	ids, err := m.windowListProperty(m.root, "_NET_CLIENT_LIST")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get window list: %w", err)
+	}
+
+	activeID, _ := m.activeWindowID()
+	windows := make([]*types.Window, 0, len(ids))
+
+	for _, id := range ids {
+		// Skip desktop/panel windows
+		if m.isDesktopOrDock(id) {
+			continue
+		}
+
+		window, err := m.getWindowInfo(id, id == activeID)
+		if err != nil {
+			// Window was most likely destroyed while we were reading it
+			continue
+		}
+		if window.Title == "" {
+			continue
+		}
+
+		if window.IsActive {
+			m.focus.observe(window)
+		}
+
+		windows = append(windows, window)
+	}
+
+	return windows, nil
+}
+
+// TakeScreenshot captures a screenshot of the root window as PNG
+func (m *X11WindowManager) TakeScreenshot() ([]byte, error) {
+	img, err := m.CaptureImage()
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(img, types.ScreenshotPNG, 0)
+}
+
+// CaptureImage reads the pixels of the root window with GetImage. Only
+// TrueColor visuals with 8 bits per channel stored in 32-bit pixels, the
+// format of every modern X server, are supported.
+func (m *X11WindowManager) CaptureImage() (image.Image, error) {
+	setup := xproto.Setup(m.conn)
+	screen := setup.DefaultScreen(m.conn)
+	width, height := int(screen.WidthInPixels), int(screen.HeightInPixels)
+
+	bitsPerPixel := 0
+	for _, format := range setup.PixmapFormats {
+		if format.Depth == screen.RootDepth {
+			bitsPerPixel = int(format.BitsPerPixel)
+		}
+	}
+	visual := rootVisual(screen)
+	if bitsPerPixel != 32 || visual == nil || visual.Class != xproto.VisualClassTrueColor {
+		return nil, fmt.Errorf("unsupported root window format: depth %d, %d bits per pixel", screen.RootDepth, bitsPerPixel)
+	}
+
+	redShift := bits.TrailingZeros32(visual.RedMask)
+	greenShift := bits.TrailingZeros32(visual.GreenMask)
+	blueShift := bits.TrailingZeros32(visual.BlueMask)
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if setup.ImageByteOrder == xproto.ImageOrderMSBFirst {
+		byteOrder = binary.BigEndian
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// The screen is read in strips to keep each reply at a few megabytes
+	rows := max(1, x11ImageStripBytes/(width*4))
+	for y := 0; y < height; y += rows {
+		stripHeight := min(rows, height-y)
+		reply, err := xproto.GetImage(m.conn, xproto.ImageFormatZPixmap, xproto.Drawable(m.root),
+			0, int16(y), uint16(width), uint16(stripHeight), 0xffffffff).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read screen: %w", err)
+		}
+		if len(reply.Data) < width*stripHeight*4 {
+			return nil, fmt.Errorf("short image reply")
+		}
+
+		pix := img.Pix[y*img.Stride:]
+		for i := 0; i < width*stripHeight; i++ {
+			pixel := byteOrder.Uint32(reply.Data[i*4:])
+			pix[i*4] = uint8(pixel >> redShift)
+			pix[i*4+1] = uint8(pixel >> greenShift)
+			pix[i*4+2] = uint8(pixel >> blueShift)
+			pix[i*4+3] = 0xff
+		}
+	}
+
+	return img, nil
+}
+
+// rootVisual returns the visual of the screen's root window
+func rootVisual(screen *xproto.ScreenInfo) *xproto.VisualInfo {
+	for _, depth := range screen.AllowedDepths {
+		for i, visual := range depth.Visuals {
+			if visual.VisualId == screen.RootVisual {
+				return &depth.Visuals[i]
+			}
+		}
+	}
+	return nil
+}
+
+// GetFocusDuration returns how long the current window has been in focus
+func (m *X11WindowManager) GetFocusDuration() time.Duration {
+	return m.focus.duration()
+}
+
+// GetMonitors returns the RandR monitor layout. Without RandR 1.5 the whole
+// screen is reported as a single monitor.
+func (m *X11WindowManager) GetMonitors() ([]types.Monitor, error) {
+	if !m.hasRandrMonitors {
+		screen := xproto.Setup(m.conn).DefaultScreen(m.conn)
+		return []types.Monitor{{
+			Index:   0,
+			Name:    "default",
+			Bounds:  types.Rectangle{Width: int(screen.WidthInPixels), Height: int(screen.HeightInPixels)},
+			Primary: true,
+		}}, nil
+	}
+
+	reply, err := randr.GetMonitors(m.conn, m.root, true).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitors: %w", err)
+	}
+
+	monitors := make([]types.Monitor, 0, len(reply.Monitors))
+	for i, info := range reply.Monitors {
+		name := fmt.Sprintf("monitor-%d", i)
+		if atomName, err := xproto.GetAtomName(m.conn, info.Name).Reply(); err == nil {
+			name = atomName.Name
+		}
+
+		monitors = append(monitors, types.Monitor{
+			Index: i,
+			Name:  name,
+			Bounds: types.Rectangle{
+				X:      int(info.X),
+				Y:      int(info.Y),
+				Width:  int(info.Width),
+				Height: int(info.Height),
+			},
+			Primary: info.Primary,
+		})
+	}
+	return monitors, nil
+}
+
+// IdleTime returns the time since the last keyboard or mouse input, as
+// tracked by the MIT-SCREEN-SAVER extension
+func (m *X11WindowManager) IdleTime() (time.Duration, error) {
+	if !m.hasScreenSaver {
+		return 0, fmt.Errorf("MIT-SCREEN-SAVER extension not available")
+	}
+
+	info, err := screensaver.QueryInfo(m.conn, xproto.Drawable(m.root)).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query screen saver info: %w", err)
+	}
+	return time.Duration(info.MsSinceUserInput) * time.Millisecond, nil
+}
+
+// WatchFocus subscribes to property changes on the root window and on the
+// focused window, reporting _NET_ACTIVE_WINDOW and title changes as they happen
+func (m *X11WindowManager) WatchFocus(ctx context.Context) (<-chan FocusEvent, error) {
+	activeAtom, err := m.atom("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return nil, err
+	}
+	netNameAtom, err := m.atom("_NET_WM_NAME")
+	if err != nil {
+		return nil, err
+	}
+	nameAtom, err := m.atom("WM_NAME")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.selectPropertyEvents(m.root, true); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to root window events: %w", err)
+	}
+
+	active, _ := m.activeWindowID()
+	if active != 0 {
+		m.selectPropertyEvents(active, true)
+	}
+
+	events := make(chan FocusEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		for {
+			ev, xerr := m.conn.WaitForEvent()
+			if ev == nil && xerr == nil {
+				// Connection closed
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			notify, ok := ev.(xproto.PropertyNotifyEvent)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case notify.Window == m.root && notify.Atom == activeAtom:
+				next, err := m.activeWindowID()
+				if err != nil || next == active {
+					continue
+				}
+				// Follow title changes of the newly focused window only
+				if active != 0 {
+					m.selectPropertyEvents(active, false)
+				}
+				if next != 0 {
+					m.selectPropertyEvents(next, true)
+				}
+				active = next
+				sendFocusEvent(events, FocusEvent{Timestamp: time.Now()})
+
+			case notify.Window == active && (notify.Atom == netNameAtom || notify.Atom == nameAtom):
+				sendFocusEvent(events, FocusEvent{Timestamp: time.Now()})
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// selectPropertyEvents enables or disables PropertyNotify events for a window
+func (m *X11WindowManager) selectPropertyEvents(id xproto.Window, enable bool) error {
+	var mask uint32
+	if enable {
+		mask = xproto.EventMaskPropertyChange
+	}
+	return xproto.ChangeWindowAttributesChecked(m.conn, id, xproto.CwEventMask, []uint32{mask}).Check()
+}
+
+// getWindowInfo reads the EWMH/ICCCM properties and geometry of a window
+func (m *X11WindowManager) getWindowInfo(id xproto.Window, isActive bool) (*types.Window, error) {
+	// WM_CLASS is "instance\0class\0" - we want the class (second part)
+	appName := "Unknown"
+	if class, err := m.property(id, "WM_CLASS"); err == nil && len(class.Value) > 0 {
+		parts := bytes.Split(bytes.TrimRight(class.Value, "\x00"), []byte{0})
+		appName = string(parts[len(parts)-1])
+	}
+
+	title := ""
+	if name, err := m.property(id, "_NET_WM_NAME"); err == nil && len(name.Value) > 0 {
+		title = string(name.Value)
+	} else if name, err := m.property(id, "WM_NAME"); err == nil {
+		title = string(name.Value)
+	}
+
+	processID := 0
+	if pid, err := m.property(id, "_NET_WM_PID"); err == nil && pid.Format == 32 && len(pid.Value) >= 4 {
+		processID = int(xgb.Get32(pid.Value))
+	}
+
+	rect, err := m.getWindowGeometry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Window{
+		AppName:    appName,
+		Title:      title,
+		ProcessID:  processID,
+		IsActive:   isActive,
+		LastActive: time.Now(),
+		Position:   rect,
+		Monitor:    0,
+	}, nil
+}
+
+// getWindowGeometry returns the window rectangle in root coordinates
+func (m *X11WindowManager) getWindowGeometry(id xproto.Window) (types.Rectangle, error) {
+	geom, err := xproto.GetGeometry(m.conn, xproto.Drawable(id)).Reply()
+	if err != nil {
+		return types.Rectangle{}, fmt.Errorf("failed to get window geometry: %w", err)
+	}
+
+	pos, err := xproto.TranslateCoordinates(m.conn, id, m.root, 0, 0).Reply()
+	if err != nil {
+		return types.Rectangle{}, fmt.Errorf("failed to translate window coordinates: %w", err)
+	}
+
+	return types.Rectangle{
+		X:      int(pos.DstX),
+		Y:      int(pos.DstY),
+		Width:  int(geom.Width),
+		Height: int(geom.Height),
+	}, nil
+}
+
+// isDesktopOrDock reports whether a window is a desktop background or panel
+func (m *X11WindowManager) isDesktopOrDock(id xproto.Window) bool {
+	windowTypes, err := m.atomListProperty(id, "_NET_WM_WINDOW_TYPE")
+	if err != nil {
+		return false
+	}
+
+	desktop, _ := m.atom("_NET_WM_WINDOW_TYPE_DESKTOP")
+	dock, _ := m.atom("_NET_WM_WINDOW_TYPE_DOCK")
+	for _, t := range windowTypes {
+		if t == desktop || t == dock {
+			return true
+		}
+	}
+	return false
+}
+
+// activeWindowID returns the window named by the root _NET_ACTIVE_WINDOW property
+func (m *X11WindowManager) activeWindowID() (xproto.Window, error) {
+	ids, err := m.windowListProperty(m.root, "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// windowListProperty reads a property holding a list of window IDs
+func (m *X11WindowManager) windowListProperty(id xproto.Window, name string) ([]xproto.Window, error) {
+	values, err := m.cardinalListProperty(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make([]xproto.Window, len(values))
+	for i, v := range values {
+		windows[i] = xproto.Window(v)
+	}
+	return windows, nil
+}
+
+// atomListProperty reads a property holding a list of atoms
+func (m *X11WindowManager) atomListProperty(id xproto.Window, name string) ([]xproto.Atom, error) {
+	values, err := m.cardinalListProperty(id, name)
+	if err != nil {
+		return nil, err
+	}
+
+	atoms := make([]xproto.Atom, len(values))
+	for i, v := range values {
+		atoms[i] = xproto.Atom(v)
+	}
+	return atoms, nil
+}
+
+// cardinalListProperty reads a property holding a list of 32-bit values
+func (m *X11WindowManager) cardinalListProperty(id xproto.Window, name string) ([]uint32, error) {
+	reply, err := m.property(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format != 32 {
+		return nil, fmt.Errorf("property %s has format %d, expected 32", name, reply.Format)
+	}
+
+	values := make([]uint32, 0, reply.ValueLen)
+	for i := 0; i+4 <= len(reply.Value) && len(values) < int(reply.ValueLen); i += 4 {
+		values = append(values, xgb.Get32(reply.Value[i:]))
+	}
+	return values, nil
+}
+
+// property reads a window property of any type
+func (m *X11WindowManager) property(id xproto.Window, name string) (*xproto.GetPropertyReply, error) {
+	atom, err := m.atom(name)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := xproto.GetProperty(m.conn, false, id, atom,
+		xproto.GetPropertyTypeAny, 0, maxPropertyLength).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get property %s: %w", name, err)
+	}
+	if reply.Type == xproto.AtomNone {
+		return nil, fmt.Errorf("property %s not set", name)
+	}
+	return reply, nil
+}
+
+// atom interns an atom name, caching the result
+func (m *X11WindowManager) atom(name string) (xproto.Atom, error) {
+	m.atomMu.Lock()
+	defer m.atomMu.Unlock()
+
+	if atom, ok := m.atoms[name]; ok {
+		return atom, nil
+	}
+
+	reply, err := xproto.InternAtom(m.conn, false, uint16(len(name)), name).Reply()
+	if err != nil {
+		return 0, fmt.Errorf("failed to intern atom %s: %w", name, err)
+	}
+
+	m.atoms[name] = reply.Atom
+	return reply.Atom, nil
+}
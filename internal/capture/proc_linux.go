@@ -0,0 +1,176 @@
+//go:build linux
+
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// clockTicks is USER_HZ, the unit of process start times in /proc/<pid>/stat.
+// It is 100 on every architecture Linux supports today.
+const clockTicks = 100
+
+var (
+	bootTimeOnce sync.Once
+	bootTime     time.Time
+)
+
+// readProcessInfo reads a process's executable, command line, working
+// directory, parent and start time from /proc. Fields that cannot be read,
+// e.g. the executable of another user's process, are left empty.
+func readProcessInfo(pid int) (*types.ProcessInfo, error) {
+	if pid <= 0 {
+		return nil, fmt.Errorf("invalid pid %d", pid)
+	}
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	fields, err := procStatFields(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &types.ProcessInfo{}
+	info.ParentPID, _ = strconv.Atoi(fields[1])
+	if ticks, err := strconv.ParseInt(fields[19], 10, 64); err == nil {
+		if boot := systemBootTime(); !boot.IsZero() {
+			info.StartTime = boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+		}
+	}
+
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		info.Executable = strings.TrimSuffix(exe, " (deleted)")
+	}
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil {
+		info.WorkingDir = cwd
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+		info.CommandLine = strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+	}
+
+	return info, nil
+}
+
+// systemBootTime returns the boot time from the btime line of /proc/stat
+func systemBootTime() time.Time {
+	bootTimeOnce.Do(func() {
+		file, err := os.Open("/proc/stat")
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if value, found := strings.CutPrefix(scanner.Text(), "btime "); found {
+				if seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+					bootTime = time.Unix(seconds, 0)
+				}
+				return
+			}
+		}
+	})
+	return bootTime
+}
+
+// readProcessTree builds the process tree from the parent PID of every
+// process in /proc
+func readProcessTree() processTree {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	tree := make(processTree)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if parent := parentPID(child); parent > 0 {
+			tree[parent] = append(tree[parent], child)
+		}
+	}
+	return tree
+}
+
+// foregroundCommand returns the command line of the job in the foreground of
+// a terminal emulator's ptys. Each pty's foreground process group is read
+// from the tpgid field of the processes below the emulator; groups led by a
+// session leader are shells waiting at the prompt and are skipped. With
+// several busy ptys (tabs) the most recently started job wins.
+func foregroundCommand(tree processTree, pid int) []string {
+	var newest *types.ProcessInfo
+	seen := make(map[int]bool)
+
+	for _, descendant := range tree.descendants(pid) {
+		fields, err := procStatFields(descendant)
+		if err != nil || fields[4] == "0" {
+			// Not attached to a terminal
+			continue
+		}
+
+		leader, err := strconv.Atoi(fields[5])
+		if err != nil || leader <= 0 || seen[leader] {
+			continue
+		}
+		seen[leader] = true
+
+		leaderFields, err := procStatFields(leader)
+		if err != nil || leaderFields[3] == strconv.Itoa(leader) {
+			continue
+		}
+
+		info, err := readProcessInfo(leader)
+		if err != nil || len(info.CommandLine) == 0 {
+			continue
+		}
+		if newest == nil || info.StartTime.After(newest.StartTime) {
+			newest = info
+		}
+	}
+
+	if newest == nil {
+		return nil
+	}
+	return newest.CommandLine
+}
+
+// parentPID reads the parent PID from /proc/<pid>/stat
+func parentPID(pid int) int {
+	fields, err := procStatFields(pid)
+	if err != nil {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+// procStatFields returns the fields of /proc/<pid>/stat that follow the
+// command name, so fields[0] is the state (field 3 in proc(5))
+func procStatFields(pid int) ([]string, error) {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+
+	// The command name in parentheses may contain spaces, so fields are
+	// counted from the last closing parenthesis
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return nil, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("invalid stat for process %d", pid)
+	}
+	return fields, nil
+}
@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/faisalahmedsifat/compass/internal/processor"
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// enrichProcesses attaches process details to every window. Windows of the
+// same process share one lookup.
+func enrichProcesses(windows []*types.Window) {
+	processes := make(map[int]*types.ProcessInfo)
+
+	for _, w := range windows {
+		if w.ProcessID <= 0 {
+			continue
+		}
+
+		info, seen := processes[w.ProcessID]
+		if !seen {
+			info, _ = readProcessInfo(w.ProcessID)
+			processes[w.ProcessID] = info
+		}
+		w.Process = info
+	}
+}
+
+// processTree maps every process to its children
+type processTree map[int][]int
+
+// descendants returns the descendants of a process, closest first
+func (t processTree) descendants(pid int) []int {
+	var descendants []int
+	queue := t[pid]
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		descendants = append(descendants, next)
+		queue = append(queue, t[next]...)
+	}
+	return descendants
+}
+
+// processTreeKey is the context key of the process tree of a snapshot
+type processTreeKey struct{}
+
+// withProcessTree returns a context carrying the process tree read for a
+// snapshot, which the enrichers of the snapshot share
+func withProcessTree(ctx context.Context, tree processTree) context.Context {
+	return context.WithValue(ctx, processTreeKey{}, tree)
+}
+
+// snapshotProcessTree returns the process tree carried by ctx, reading it
+// when there is none
+func snapshotProcessTree(ctx context.Context) processTree {
+	if tree, ok := ctx.Value(processTreeKey{}).(processTree); ok {
+		return tree
+	}
+	return readProcessTree()
+}
+
+// detectForegroundCommands records what each terminal window is running.
+// The arguments of the commands are redacted by filter unless it is nil.
+func detectForegroundCommands(windows []*types.Window, tree processTree, filter *PrivacyFilter) {
+	for _, w := range windows {
+		if w.ProcessID <= 0 || !processor.IsTerminal(*w) {
+			continue
+		}
+		if command := foregroundCommand(tree, w.ProcessID); len(command) > 0 {
+			if filter != nil {
+				command = filter.redactCommandLine(w.AppName, command)
+			}
+			w.Command = formatCommand(command)
+		}
+	}
+}
+
+// formatCommand joins a command line, dropping the directory of the program
+// so "/usr/bin/ssh prod-db" reads "ssh prod-db"
+func formatCommand(commandLine []string) string {
+	args := append([]string{filepath.Base(commandLine[0])}, commandLine[1:]...)
+	return strings.Join(args, " ")
+}
+
+// detectRepository finds the git checkout a window works in. Descendants are
+// checked newest first before the window's own process, since shells and
+// language servers run inside the project while the host process usually
+// stays in the directory it was launched from. Windows whose working
+// directory is unknown or was redacted are skipped.
+func detectRepository(window *types.Window, tree processTree) *types.Repository {
+	if window.Process == nil || window.Process.WorkingDir == "" {
+		return nil
+	}
+
+	var children []*types.ProcessInfo
+	for _, pid := range tree.descendants(window.ProcessID) {
+		if info, err := readProcessInfo(pid); err == nil && info.WorkingDir != "" {
+			children = append(children, info)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].StartTime.After(children[j].StartTime)
+	})
+
+	for _, child := range children {
+		if repo := findRepository(child.WorkingDir); repo != nil {
+			return repo
+		}
+	}
+	return findRepository(window.Process.WorkingDir)
+}
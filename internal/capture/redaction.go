@@ -0,0 +1,179 @@
+package capture
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// redactedPlaceholder replaces matches of mask rules with a pattern
+const redactedPlaceholder = "[REDACTED]"
+
+// secretFlag matches options whose value is a secret, e.g. --token or
+// --db-password=…, capturing the option and any value given after "="
+var secretFlag = regexp.MustCompile(`(?i)^(--?[a-z0-9_-]*(?:token|secret|passw(?:or)?d|api[_-]?key|access[_-]?key))(=.*)?$`)
+
+// secretHeader matches HTTP headers carrying credentials as passed to tools
+// like curl, e.g. "Authorization: Basic …", capturing the header name
+var secretHeader = regexp.MustCompile(`(?i)^\s*((?:proxy-)?authorization|cookie|x-[a-z0-9-]*(?:token|key|secret))\s*:`)
+
+// detector is a built-in redaction pattern. mask is the template the mask
+// action replaces a match with; valid rejects matches the expression alone
+// cannot rule out.
+type detector struct {
+	pattern *regexp.Regexp
+	mask    string
+	valid   func(match string) bool
+}
+
+// detectors are the built-in patterns selectable by RedactionRule.Detector
+var detectors = map[string]*detector{
+	types.DetectEmail: {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		mask:    "[EMAIL]",
+	},
+	types.DetectToken: {
+		pattern: regexp.MustCompile(`\b(?:` +
+			`(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}` + // Stripe
+			`|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}` + // GitHub
+			`|glpat-[A-Za-z0-9_-]{20,}` + // GitLab
+			`|xox[abposr]-[A-Za-z0-9-]{10,}` + // Slack
+			`|(?:AKIA|ASIA)[0-9A-Z]{16}` + // AWS access key IDs
+			`|AIza[0-9A-Za-z_-]{35}` + // Google API keys
+			`|eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}` + // JWTs
+			`|(?i:bearer)\s+[A-Za-z0-9._~+/-]{16,}=*` + // Authorization headers
+			`|(?i:[a-z0-9_-]*(?:token|secret|passw(?:or)?d|api[_-]?key|access[_-]?key))` +
+			`["']?(?:\s*=\s*|:)["']?[^\s"'&,;]{6,}` + // Values of keys, e.g. token=…
+			`)`),
+		mask: "[TOKEN]",
+	},
+	types.DetectCreditCard: {
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		mask:    "[CARD]",
+		valid:   luhnValid,
+	},
+	types.DetectURLQuery: {
+		pattern: regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^\s?#]+)\?[^\s#]+`),
+		mask:    "${1}?[QUERY]",
+	},
+}
+
+// redactionRule is a compiled RedactionRule
+type redactionRule struct {
+	action   string
+	pattern  *regexp.Regexp
+	template string // Expanded for each match by mask and replace
+	valid    func(match string) bool
+	apps     map[string]bool
+}
+
+// compileRedactionRule compiles a redaction rule from the configuration
+func compileRedactionRule(rule types.RedactionRule) (*redactionRule, error) {
+	compiled := &redactionRule{
+		action:   rule.Action,
+		template: redactedPlaceholder,
+	}
+
+	switch {
+	case rule.Detector != "":
+		d, ok := detectors[rule.Detector]
+		if !ok {
+			return nil, fmt.Errorf("unknown detector: %s", rule.Detector)
+		}
+		compiled.pattern, compiled.template, compiled.valid = d.pattern, d.mask, d.valid
+	case rule.Pattern != "":
+		pattern, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+		}
+		compiled.pattern = pattern
+	default:
+		return nil, fmt.Errorf("rule needs a pattern or a detector")
+	}
+
+	switch rule.Action {
+	case types.RedactMask, types.RedactHash, types.RedactDrop:
+	case types.RedactReplace:
+		compiled.template = rule.Replacement
+	default:
+		return nil, fmt.Errorf("unknown redaction action: %s", rule.Action)
+	}
+
+	if len(rule.Apps) > 0 {
+		compiled.apps = make(map[string]bool, len(rule.Apps))
+		for _, app := range rule.Apps {
+			compiled.apps[strings.ToLower(app)] = true
+		}
+	}
+	return compiled, nil
+}
+
+// appliesTo reports whether the rule is scoped to include an application
+func (r *redactionRule) appliesTo(appName string) bool {
+	return r.apps == nil || r.apps[strings.ToLower(appName)]
+}
+
+// apply redacts a title, reporting whether the window is to be dropped
+func (r *redactionRule) apply(title string, salt []byte) (string, bool) {
+	var matches [][]int
+	for _, match := range r.pattern.FindAllStringSubmatchIndex(title, -1) {
+		if r.valid == nil || r.valid(title[match[0]:match[1]]) {
+			matches = append(matches, match)
+		}
+	}
+	if len(matches) == 0 {
+		return title, false
+	}
+
+	switch r.action {
+	case types.RedactDrop:
+		return "", true
+	case types.RedactHash:
+		return hashTitle(title, salt), false
+	}
+
+	var redacted []byte
+	last := 0
+	for _, match := range matches {
+		redacted = append(redacted, title[last:match[0]]...)
+		redacted = r.pattern.ExpandString(redacted, r.template, title, match)
+		last = match[1]
+	}
+	redacted = append(redacted, title[last:]...)
+	return string(redacted), false
+}
+
+// hashTitle replaces a title with a keyed hash of it. Equal titles hash
+// alike within an installation, so time per document can still be told
+// apart, while the salt keeps guessed titles from being confirmed.
+func hashTitle(title string, salt []byte) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(title))
+	return "[hash:" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum
+// card numbers carry
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
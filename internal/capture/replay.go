@@ -0,0 +1,221 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// recordedFrame is one line of a recording: what the window manager
+// reported at one instant
+type recordedFrame struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	Windows       []*types.Window `json:"windows"`
+	Active        *types.Window   `json:"active,omitempty"`
+	FocusDuration time.Duration   `json:"focus_duration"`
+	Monitors      []types.Monitor `json:"monitors,omitempty"`
+	Idle          *time.Duration  `json:"idle,omitempty"`
+	Error         string          `json:"error,omitempty"` // GetAllWindows failure
+}
+
+// Record samples the platform window manager every interval and writes one
+// JSON line per sample until ctx is cancelled. Windows are written with their
+// process details and terminal commands, which are looked up on this machine
+// and cannot be recovered when the recording is replayed elsewhere. Nothing is
+// redacted; the privacy settings apply when the recording is replayed.
+func Record(ctx context.Context, config *types.Config, w io.Writer, interval time.Duration) error {
+	windowMgr := newPlatformWindowManager(config.Tracking)
+	if closer, ok := windowMgr.(io.Closer); ok {
+		defer closer.Close()
+	}
+	idleDetector := selectIdleDetector(windowMgr)
+
+	encoder := json.NewEncoder(w)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		frame := recordedFrame{Timestamp: time.Now()}
+
+		windows, err := windowMgr.GetAllWindows()
+		if err != nil {
+			frame.Error = err.Error()
+		} else {
+			enrichProcesses(windows)
+			tree := readProcessTree()
+			detectForegroundCommands(windows, tree, nil)
+			if config.Privacy.TrackConnections {
+				detectConnections(windows, tree)
+			}
+			frame.Windows = windows
+		}
+
+		if active, err := windowMgr.GetActiveWindow(); err == nil {
+			frame.Active = active
+			for _, window := range windows {
+				if window.IsActive {
+					frame.Active = window
+					break
+				}
+			}
+		}
+		frame.FocusDuration = windowMgr.GetFocusDuration()
+
+		if provider, ok := windowMgr.(MonitorProvider); ok {
+			frame.Monitors, _ = provider.GetMonitors()
+		}
+		if idleDetector != nil {
+			if idle, err := idleDetector.IdleTime(); err == nil {
+				frame.Idle = &idle
+			}
+		}
+
+		if err := encoder.Encode(&frame); err != nil {
+			return fmt.Errorf("failed to write frame: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ReplayWindowManager implements WindowManager by playing back a recording.
+// At any virtual time it reports the latest frame recorded at or before it.
+type ReplayWindowManager struct {
+	frames []recordedFrame
+	clock  *VirtualClock
+}
+
+// LoadRecording reads a recording written by Record
+func LoadRecording(path string) (*ReplayWindowManager, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	return NewReplayWindowManager(file)
+}
+
+// NewReplayWindowManager reads a recording from r. Its clock starts at the
+// first frame and ends at the last one.
+func NewReplayWindowManager(r io.Reader) (*ReplayWindowManager, error) {
+	var frames []recordedFrame
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame recordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("invalid frame on line %d: %w", line, err)
+		}
+		frames = append(frames, frame)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("recording is empty")
+	}
+
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Timestamp.Before(frames[j].Timestamp)
+	})
+
+	return &ReplayWindowManager{
+		frames: frames,
+		clock:  NewVirtualClock(frames[0].Timestamp, frames[len(frames)-1].Timestamp),
+	}, nil
+}
+
+// Clock returns the virtual clock the recording is played against
+func (m *ReplayWindowManager) Clock() *VirtualClock {
+	return m.clock
+}
+
+// Start returns the time of the first frame
+func (m *ReplayWindowManager) Start() time.Time {
+	return m.frames[0].Timestamp
+}
+
+// End returns the time of the last frame
+func (m *ReplayWindowManager) End() time.Time {
+	return m.frames[len(m.frames)-1].Timestamp
+}
+
+// GetActiveWindow returns the recorded focused window
+func (m *ReplayWindowManager) GetActiveWindow() (*types.Window, error) {
+	frame := m.current()
+	if frame.Active == nil {
+		return nil, fmt.Errorf("no active window found")
+	}
+	window := *frame.Active
+	return &window, nil
+}
+
+// GetAllWindows returns copies of the recorded windows, or the recorded error
+func (m *ReplayWindowManager) GetAllWindows() ([]*types.Window, error) {
+	frame := m.current()
+	if frame.Error != "" {
+		return nil, fmt.Errorf("%s", frame.Error)
+	}
+
+	windows := make([]*types.Window, len(frame.Windows))
+	for i, w := range frame.Windows {
+		window := *w
+		windows[i] = &window
+	}
+	return windows, nil
+}
+
+// TakeScreenshot always fails; recordings hold no images
+func (m *ReplayWindowManager) TakeScreenshot() ([]byte, error) {
+	return nil, fmt.Errorf("screenshots are not recorded")
+}
+
+// GetFocusDuration returns the recorded focus duration advanced to the
+// current virtual time
+func (m *ReplayWindowManager) GetFocusDuration() time.Duration {
+	frame := m.current()
+	return frame.FocusDuration + m.clock.Now().Sub(frame.Timestamp)
+}
+
+// GetMonitors returns the recorded monitor layout
+func (m *ReplayWindowManager) GetMonitors() ([]types.Monitor, error) {
+	return m.current().Monitors, nil
+}
+
+// IdleTime returns the recorded idle time advanced to the current virtual
+// time, or an error when the recording has none
+func (m *ReplayWindowManager) IdleTime() (time.Duration, error) {
+	frame := m.current()
+	if frame.Idle == nil {
+		return 0, fmt.Errorf("idle time was not recorded")
+	}
+	return *frame.Idle + m.clock.Now().Sub(frame.Timestamp), nil
+}
+
+// current returns the latest frame at or before the virtual time
+func (m *ReplayWindowManager) current() *recordedFrame {
+	now := m.clock.Now()
+	i := sort.Search(len(m.frames), func(i int) bool {
+		return m.frames[i].Timestamp.After(now)
+	})
+	if i > 0 {
+		i--
+	}
+	return &m.frames[i]
+}
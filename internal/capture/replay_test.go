@@ -0,0 +1,130 @@
+package capture
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRecording has frames 10 seconds apart, written out of order: code is
+// focused, then Slack after idling, then the window list fails
+const testRecording = `
+{"timestamp": "2025-03-10T09:00:10Z", "windows": [{"app_name": "code", "title": "main.go", "pid": 1}, {"app_name": "Slack", "title": "general", "pid": 2, "is_active": true}], "active": {"app_name": "Slack", "title": "general", "pid": 2, "is_active": true}, "focus_duration": 0, "idle": 60000000000}
+{"timestamp": "2025-03-10T09:00:00Z", "windows": [{"app_name": "code", "title": "main.go", "pid": 1, "is_active": true}], "active": {"app_name": "code", "title": "main.go", "pid": 1, "is_active": true}, "focus_duration": 30000000000, "monitors": [{"index": 0, "name": "DP-1", "bounds": {"x": 0, "y": 0, "width": 1920, "height": 1080}, "primary": true}]}
+
+{"timestamp": "2025-03-10T09:00:20Z", "error": "no display"}
+`
+
+func loadTestRecording(t *testing.T) (*ReplayWindowManager, Ticker) {
+	t.Helper()
+
+	m, err := NewReplayWindowManager(strings.NewReader(testRecording))
+	if err != nil {
+		t.Fatalf("NewReplayWindowManager: %v", err)
+	}
+	ticker := m.Clock().NewTicker(5 * time.Second)
+	t.Cleanup(ticker.Stop)
+	return m, ticker
+}
+
+// advance moves the virtual clock to the next tick
+func advance(m *ReplayWindowManager, ticker Ticker) {
+	<-ticker.C()
+	m.Clock().Now()
+}
+
+func TestReplayWindowManagerBounds(t *testing.T) {
+	m, _ := loadTestRecording(t)
+
+	if want := clockStart; !m.Start().Equal(want) {
+		t.Errorf("Start() = %v, want %v", m.Start(), want)
+	}
+	if want := clockStart.Add(20 * time.Second); !m.End().Equal(want) {
+		t.Errorf("End() = %v, want %v", m.End(), want)
+	}
+	if !m.Clock().Now().Equal(m.Start()) {
+		t.Errorf("clock starts at %v, want %v", m.Clock().Now(), m.Start())
+	}
+}
+
+func TestReplayWindowManagerPlaysFrames(t *testing.T) {
+	m, ticker := loadTestRecording(t)
+
+	active, err := m.GetActiveWindow()
+	if err != nil || active.AppName != "code" {
+		t.Fatalf("GetActiveWindow() = %v, %v; want code", active, err)
+	}
+	monitors, _ := m.GetMonitors()
+	if len(monitors) != 1 || monitors[0].Name != "DP-1" {
+		t.Errorf("GetMonitors() = %v, want DP-1", monitors)
+	}
+	if _, err := m.IdleTime(); err == nil {
+		t.Error("IdleTime() succeeded for a frame without idle time")
+	}
+
+	// Between frames the latest one is played, its durations advanced
+	advance(m, ticker)
+	if active, _ := m.GetActiveWindow(); active.AppName != "code" {
+		t.Errorf("active window at 5s = %s, want code", active.AppName)
+	}
+	if got, want := m.GetFocusDuration(), 35*time.Second; got != want {
+		t.Errorf("GetFocusDuration() at 5s = %v, want %v", got, want)
+	}
+
+	advance(m, ticker)
+	windows, err := m.GetAllWindows()
+	if err != nil || len(windows) != 2 {
+		t.Fatalf("GetAllWindows() at 10s = %d windows, %v; want 2", len(windows), err)
+	}
+	if active, _ := m.GetActiveWindow(); active.AppName != "Slack" {
+		t.Errorf("active window at 10s = %s, want Slack", active.AppName)
+	}
+
+	advance(m, ticker)
+	if got, want := m.GetFocusDuration(), 5*time.Second; got != want {
+		t.Errorf("GetFocusDuration() at 15s = %v, want %v", got, want)
+	}
+	if idle, err := m.IdleTime(); err != nil || idle != 65*time.Second {
+		t.Errorf("IdleTime() at 15s = %v, %v; want 65s", idle, err)
+	}
+
+	advance(m, ticker)
+	if _, err := m.GetAllWindows(); err == nil || err.Error() != "no display" {
+		t.Errorf("GetAllWindows() at 20s error = %v, want the recorded one", err)
+	}
+}
+
+func TestReplayWindowManagerReturnsCopies(t *testing.T) {
+	m, _ := loadTestRecording(t)
+
+	windows, _ := m.GetAllWindows()
+	windows[0].Title = "changed"
+	active, _ := m.GetActiveWindow()
+	active.Title = "changed"
+
+	windows, _ = m.GetAllWindows()
+	active, _ = m.GetActiveWindow()
+	if windows[0].Title != "main.go" || active.Title != "main.go" {
+		t.Error("changing returned windows changed the recording")
+	}
+}
+
+func TestNewReplayWindowManagerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		recording string
+		want      string
+	}{
+		{"empty", "\n\n", "recording is empty"},
+		{"invalid frame", `{"timestamp": "2025-03-10T09:00:00Z"}` + "\nnot json\n", "line 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReplayWindowManager(strings.NewReader(tt.recording))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
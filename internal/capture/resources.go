@@ -0,0 +1,93 @@
+package capture
+
+import (
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// processUsage is the cumulative resource usage of a process tree
+type processUsage struct {
+	// startTime of the root process tells a reused PID apart
+	startTime  string
+	processes  int
+	cpuTime    time.Duration
+	rssBytes   uint64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+// resourceSampler turns the cumulative usage of window process trees into
+// samples covering the time since the previous sample
+type resourceSampler struct {
+	minInterval time.Duration
+	lastSample  time.Time
+	previous    map[int]*processUsage
+}
+
+// newResourceSampler creates a sampler taking at most one sample per
+// minInterval
+func newResourceSampler(minInterval time.Duration) *resourceSampler {
+	return &resourceSampler{
+		minInterval: minInterval,
+		previous:    make(map[int]*processUsage),
+	}
+}
+
+// sample measures the process tree of every window's process. Processes seen
+// for the first time produce no sample, as there is no interval to measure.
+func (s *resourceSampler) sample(windows []types.Window, tree processTree, now time.Time) []types.ResourceSample {
+	if !s.lastSample.IsZero() && now.Sub(s.lastSample) < s.minInterval {
+		return nil
+	}
+	elapsed := now.Sub(s.lastSample)
+	s.lastSample = now
+
+	appNames := make(map[int]string)
+	pids := make([]int, 0, len(windows))
+	for _, w := range windows {
+		if _, seen := appNames[w.ProcessID]; w.ProcessID > 0 && !seen {
+			appNames[w.ProcessID] = w.AppName
+			pids = append(pids, w.ProcessID)
+		}
+	}
+
+	usages := readProcessUsages(pids, tree)
+
+	var samples []types.ResourceSample
+	for _, pid := range pids {
+		usage, ok := usages[pid]
+		if !ok {
+			continue
+		}
+
+		previous := s.previous[pid]
+		if previous == nil || previous.startTime != usage.startTime {
+			continue
+		}
+
+		samples = append(samples, types.ResourceSample{
+			Timestamp:  now,
+			Duration:   elapsed,
+			AppName:    appNames[pid],
+			ProcessID:  pid,
+			Processes:  usage.processes,
+			CPUPercent: float64(positiveDelta(uint64(usage.cpuTime), uint64(previous.cpuTime))) / float64(elapsed) * 100,
+			RSSBytes:   usage.rssBytes,
+			ReadBytes:  positiveDelta(usage.readBytes, previous.readBytes),
+			WriteBytes: positiveDelta(usage.writeBytes, previous.writeBytes),
+		})
+	}
+
+	s.previous = usages
+	return samples
+}
+
+// positiveDelta returns current - previous, or 0 when the counter went
+// backwards because processes left the tree
+func positiveDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+	return current - previous
+}
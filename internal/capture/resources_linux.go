@@ -0,0 +1,88 @@
+//go:build linux
+
+package capture
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcessUsages reads the cumulative resource usage of each process and
+// its descendants in tree
+func readProcessUsages(pids []int, tree processTree) map[int]*processUsage {
+	usages := make(map[int]*processUsage, len(pids))
+
+	for _, pid := range pids {
+		fields, err := procStatFields(pid)
+		if err != nil {
+			continue
+		}
+
+		usage := &processUsage{startTime: fields[19]}
+		for _, member := range append([]int{pid}, tree.descendants(pid)...) {
+			addProcessUsage(usage, member)
+		}
+		usages[pid] = usage
+	}
+
+	return usages
+}
+
+// addProcessUsage adds one process's CPU time, resident memory and I/O to
+// usage. CPU time includes the time of reaped children (cutime, cstime), so
+// short-lived build processes are still counted after they exit.
+func addProcessUsage(usage *processUsage, pid int) {
+	fields, err := procStatFields(pid)
+	if err != nil {
+		// Exited since the tree was read
+		return
+	}
+
+	usage.processes++
+	for _, field := range fields[11:15] {
+		if ticks, err := strconv.ParseUint(field, 10, 64); err == nil {
+			usage.cpuTime += time.Duration(ticks) * time.Second / clockTicks
+		}
+	}
+
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	if status := readProcValues(filepath.Join(dir, "status")); status != nil {
+		// Reported in kB
+		usage.rssBytes += status["VmRSS"] * 1024
+	}
+	// io is only readable for our own processes
+	if io := readProcValues(filepath.Join(dir, "io")); io != nil {
+		usage.readBytes += io["read_bytes"]
+		usage.writeBytes += io["write_bytes"]
+	}
+}
+
+// readProcValues reads the numeric "key: value" lines of a /proc file
+func readProcValues(path string) map[string]uint64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		if number, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[key] = number
+		}
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package capture
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/internal/schedule"
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// offSchedule is the away category of time outside the tracking schedule.
+// Unlike other away periods it is not recorded at all.
+const offSchedule = "Off schedule"
+
+// scheduleRequest asks the capture loop to track outside the schedule until
+// a time, the zero time ending an override
+type scheduleRequest struct {
+	until time.Time
+	reply chan types.ScheduleStatus
+}
+
+// OverrideSchedule tracks outside the schedule for d, or until the schedule
+// next starts when d is zero
+func (c *CaptureEngine) OverrideSchedule(d time.Duration) (types.ScheduleStatus, error) {
+	if c.schedule == nil {
+		return types.ScheduleStatus{}, fmt.Errorf("no tracking schedule is configured")
+	}
+	if d < 0 {
+		return types.ScheduleStatus{}, fmt.Errorf("override duration cannot be negative")
+	}
+
+	now := c.clock.Now()
+	until := now.Add(d)
+	if d == 0 {
+		if until = c.schedule.NextStart(now); until.IsZero() {
+			return types.ScheduleStatus{}, fmt.Errorf("the tracking schedule does not start again, give a duration")
+		}
+	}
+	return c.requestScheduleOverride(until)
+}
+
+// ClearScheduleOverride returns to tracking only within the schedule
+func (c *CaptureEngine) ClearScheduleOverride() (types.ScheduleStatus, error) {
+	return c.requestScheduleOverride(time.Time{})
+}
+
+// ScheduleStatus returns whether tracking runs under the schedule and when
+// that next changes
+func (c *CaptureEngine) ScheduleStatus() types.ScheduleStatus {
+	return c.schedule.Status(c.clock.Now(), c.overrideUntil())
+}
+
+// requestScheduleOverride hands an override to the capture loop and waits
+// for the result
+func (c *CaptureEngine) requestScheduleOverride(until time.Time) (types.ScheduleStatus, error) {
+	request := scheduleRequest{until: until, reply: make(chan types.ScheduleStatus, 1)}
+
+	select {
+	case c.scheduleRequests <- request:
+	case <-time.After(pauseRequestTimeout):
+		return types.ScheduleStatus{}, fmt.Errorf("capture engine is not running")
+	}
+	return <-request.reply, nil
+}
+
+// handleScheduleRequest changes the override on the capture loop
+func (c *CaptureEngine) handleScheduleRequest(request scheduleRequest) {
+	c.scheduleMu.Lock()
+	c.scheduleOverride = request.until
+	c.scheduleMu.Unlock()
+
+	if err := c.storage.SaveScheduleOverride(request.until); err != nil {
+		log.Printf("Failed to save schedule override: %v", err)
+	}
+
+	c.applySchedule(c.clock.Now())
+	request.reply <- c.ScheduleStatus()
+}
+
+// setupSchedule loads the schedule and a stored override and starts out
+// suspended when tracking is outside it
+func (c *CaptureEngine) setupSchedule() {
+	s, err := schedule.New(c.config.Tracking.Schedule)
+	if err != nil {
+		log.Printf("Ignoring invalid tracking schedule: %v", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+	c.schedule = s
+
+	now := c.clock.Now()
+	override, err := c.storage.GetScheduleOverride()
+	if err != nil {
+		log.Printf("Failed to read schedule override: %v", err)
+	}
+	if override.After(now) {
+		c.scheduleOverride = override
+	}
+
+	c.outsideSchedule = !s.Tracking(now, c.scheduleOverride)
+	c.nextScheduleChange = s.NextChange(now, c.scheduleOverride)
+	if c.outsideSchedule {
+		c.awaySince = now
+		if !c.nextScheduleChange.IsZero() {
+			log.Printf("Outside the tracking schedule until %s", c.nextScheduleChange.Local().Format("Mon 15:04"))
+		}
+	}
+}
+
+// checkSchedule applies the schedule changes due by now at the time each
+// was due
+func (c *CaptureEngine) checkSchedule(now time.Time) {
+	for !c.nextScheduleChange.IsZero() && !now.Before(c.nextScheduleChange) {
+		c.applySchedule(c.nextScheduleChange)
+	}
+}
+
+// applySchedule suspends or resumes capture as the schedule says at at and
+// finds its next change
+func (c *CaptureEngine) applySchedule(at time.Time) {
+	override := c.overrideUntil()
+	outside := !c.schedule.Tracking(at, override)
+	c.nextScheduleChange = c.schedule.NextChange(at, override)
+	if outside == c.outsideSchedule {
+		return
+	}
+
+	reason := "Tracking allowed by the schedule"
+	if outside {
+		reason = "Outside the tracking schedule"
+	}
+	c.changeAway(at, reason, func() {
+		c.outsideSchedule = outside
+	})
+}
+
+// overrideUntil returns until when tracking runs outside the schedule
+func (c *CaptureEngine) overrideUntil() time.Time {
+	c.scheduleMu.Lock()
+	defer c.scheduleMu.Unlock()
+	return c.scheduleOverride
+}
@@ -0,0 +1,209 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+	"golang.org/x/image/draw"
+)
+
+// thumbnailQuality is the JPEG quality of thumbnails, which are only ever
+// shown small
+const thumbnailQuality = 60
+
+// pixelateBlockSize is the edge of the squares sensitive regions are
+// pixelated into, coarse enough that text in them cannot be read
+const pixelateBlockSize = 24
+
+// ImageCapturer is implemented by window managers that capture the screen
+// in-process. The engine prefers it over TakeScreenshot, which returns an
+// encoded image that would have to be decoded again for scaling.
+type ImageCapturer interface {
+	CaptureImage() (image.Image, error)
+}
+
+// captureScreenshot takes a screenshot for a snapshot, pixelates the windows
+// the privacy filter hides and encodes it and its thumbnail as configured.
+// windows are the unfiltered windows on screen. A screenshot near-identical
+// to the last stored one is not encoded; the snapshot references the stored
+// one instead. The thumbnail is nil when thumbnails are disabled.
+func (c *CaptureEngine) captureScreenshot(snapshot *types.WorkspaceSnapshot, windows []*types.Window) error {
+	regions, ok := c.privacyFilter.SensitiveRegions(windows, snapshot.Monitors)
+	if !ok {
+		return fmt.Errorf("skipped, a sensitive window is fullscreen or has no known geometry")
+	}
+
+	img, err := c.captureImage()
+	if err != nil {
+		return err
+	}
+	if img, err = c.privacyFilter.BlurSensitive(img, regions, snapshot.Monitors); err != nil {
+		return err
+	}
+
+	hash := differenceHash(img)
+	if c.isDuplicateScreenshot(hash) {
+		snapshot.ScreenshotID = c.lastScreenshotID
+		return nil
+	}
+
+	tracking := c.config.Tracking
+	img = scaleToFit(img, tracking.ScreenshotMaxSize)
+	full, err := encodeImage(img, tracking.ScreenshotFormat, tracking.ScreenshotQuality)
+	if err != nil {
+		return err
+	}
+
+	var thumbnail []byte
+	if tracking.ThumbnailSize > 0 {
+		thumb := scaleToFit(img, tracking.ThumbnailSize)
+		if thumbnail, err = encodeImage(thumb, types.ScreenshotJPEG, thumbnailQuality); err != nil {
+			return err
+		}
+	}
+
+	snapshot.Screenshot = full
+	snapshot.Thumbnail = thumbnail
+	snapshot.ScreenshotHash = hash
+	return nil
+}
+
+// captureImage grabs the screen from the window manager
+func (c *CaptureEngine) captureImage() (image.Image, error) {
+	if capturer, ok := c.windowMgr.(ImageCapturer); ok {
+		return capturer.CaptureImage()
+	}
+
+	data, err := c.windowMgr.TakeScreenshot()
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	return img, nil
+}
+
+// scaleToFit shrinks an image so its longest edge is at most maxSize pixels,
+// keeping the aspect ratio. Smaller images and a zero maxSize leave it as is.
+func scaleToFit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.BiLinear.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// screenToImage maps screen rectangles onto the pixels of a screenshot of
+// the whole monitor layout, scaling for compositors that capture at a
+// different resolution than they lay windows out in. Without a layout the
+// image is taken to start at the screen origin at one pixel per unit.
+// Rectangles are clipped to the image and dropped when outside it.
+func screenToImage(regions []types.Rectangle, monitors []types.Monitor, bounds image.Rectangle) []image.Rectangle {
+	screen := image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+	if len(monitors) > 0 {
+		screen = image.Rectangle{}
+		for _, monitor := range monitors {
+			screen = screen.Union(toImageRect(monitor.Bounds))
+		}
+	}
+	if screen.Empty() {
+		return nil
+	}
+
+	scaleX := float64(bounds.Dx()) / float64(screen.Dx())
+	scaleY := float64(bounds.Dy()) / float64(screen.Dy())
+	rects := make([]image.Rectangle, 0, len(regions))
+	for _, region := range regions {
+		r := toImageRect(region).Sub(screen.Min)
+		// Round outwards so scaling never uncovers an edge of the window
+		rect := image.Rect(
+			bounds.Min.X+int(math.Floor(float64(r.Min.X)*scaleX)),
+			bounds.Min.Y+int(math.Floor(float64(r.Min.Y)*scaleY)),
+			bounds.Min.X+int(math.Ceil(float64(r.Max.X)*scaleX)),
+			bounds.Min.Y+int(math.Ceil(float64(r.Max.Y)*scaleY)),
+		).Intersect(bounds)
+		if !rect.Empty() {
+			rects = append(rects, rect)
+		}
+	}
+	return rects
+}
+
+// toImageRect converts a Rectangle to an image.Rectangle
+func toImageRect(r types.Rectangle) image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+}
+
+// pixelate returns a copy of img with each rectangle replaced by squares of
+// pixelateBlockSize filled with their average colour
+func pixelate(img image.Image, rects []image.Rectangle) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+
+	for _, rect := range rects {
+		rect = rect.Intersect(bounds)
+		for y := rect.Min.Y; y < rect.Max.Y; y += pixelateBlockSize {
+			for x := rect.Min.X; x < rect.Max.X; x += pixelateBlockSize {
+				block := image.Rect(x, y, x+pixelateBlockSize, y+pixelateBlockSize).Intersect(rect)
+				draw.Draw(out, block, &image.Uniform{averageColor(out, block)}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return out
+}
+
+// averageColor returns the mean colour of a block of an image
+func averageColor(img *image.RGBA, block image.Rectangle) color.RGBA {
+	var r, g, b, a, n uint64
+	for y := block.Min.Y; y < block.Max.Y; y++ {
+		for x := block.Min.X; x < block.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			r, g, b, a = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)}
+}
+
+// encodeImage encodes an image in a screenshot format
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case types.ScreenshotPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+		err = encoder.Encode(&buf, img)
+	case types.ScreenshotJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	default:
+		return nil, fmt.Errorf("unknown screenshot format: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
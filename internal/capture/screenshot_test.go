@@ -0,0 +1,235 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// stripes returns an image of one-pixel black and white vertical stripes,
+// standing in for text a pixelated region must not show
+func stripes(bounds image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if x%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestPixelate(t *testing.T) {
+	bounds := image.Rect(100, 50, 300, 200)
+	img := stripes(bounds)
+	original := stripes(bounds)
+
+	// The rectangle starts off the block grid and reaches past the image
+	rect := image.Rect(110, 60, 400, 100)
+	out := pixelate(img, []image.Rectangle{rect})
+
+	if !reflect.DeepEqual(img.Pix, original.Pix) {
+		t.Fatal("pixelate modified its input")
+	}
+	if out.Bounds() != bounds {
+		t.Fatalf("output bounds = %v, want %v", out.Bounds(), bounds)
+	}
+
+	inside := rect.Intersect(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			got := out.RGBAAt(x, y)
+			if !image.Pt(x, y).In(inside) {
+				if want := original.RGBAAt(x, y); got != want {
+					t.Fatalf("pixel (%d,%d) outside the rectangle = %v, want %v", x, y, got, want)
+				}
+				continue
+			}
+
+			// Blocks are aligned to the rectangle and filled with the
+			// average of the stripes
+			block := image.Pt(x-(x-inside.Min.X)%pixelateBlockSize, y-(y-inside.Min.Y)%pixelateBlockSize)
+			if want := out.RGBAAt(block.X, block.Y); got != want {
+				t.Fatalf("pixel (%d,%d) = %v, want its block's %v", x, y, got, want)
+			}
+			if got.R < 100 || got.R > 155 {
+				t.Fatalf("pixel (%d,%d) = %v, want a grey average", x, y, got)
+			}
+		}
+	}
+}
+
+func TestScreenToImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		regions  []types.Rectangle
+		monitors []types.Monitor
+		bounds   image.Rectangle
+		want     []image.Rectangle
+	}{
+		{
+			name:    "no layout",
+			regions: []types.Rectangle{{X: 10, Y: 20, Width: 100, Height: 50}},
+			bounds:  image.Rect(0, 0, 1920, 1080),
+			want:    []image.Rectangle{image.Rect(10, 20, 110, 70)},
+		},
+		{
+			name:    "monitor left of the origin",
+			regions: []types.Rectangle{{X: 0, Y: 0, Width: 100, Height: 100}, {X: -1920, Y: 0, Width: 10, Height: 10}},
+			monitors: []types.Monitor{
+				{Index: 0, Bounds: types.Rectangle{X: -1920, Y: 0, Width: 1920, Height: 1080}},
+				{Index: 1, Bounds: types.Rectangle{X: 0, Y: 0, Width: 2560, Height: 1440}},
+			},
+			bounds: image.Rect(0, 0, 4480, 1440),
+			want:   []image.Rectangle{image.Rect(1920, 0, 2020, 100), image.Rect(0, 0, 10, 10)},
+		},
+		{
+			name:     "captured at twice the layout resolution",
+			regions:  []types.Rectangle{{X: 10, Y: 10, Width: 100, Height: 50}},
+			monitors: []types.Monitor{{Bounds: types.Rectangle{Width: 1440, Height: 900}}},
+			bounds:   image.Rect(0, 0, 2880, 1800),
+			want:     []image.Rectangle{image.Rect(20, 20, 220, 120)},
+		},
+		{
+			name:     "fractional scale rounds outwards",
+			regions:  []types.Rectangle{{X: 1, Y: 1, Width: 1, Height: 1}},
+			monitors: []types.Monitor{{Bounds: types.Rectangle{Width: 1280, Height: 720}}},
+			bounds:   image.Rect(0, 0, 1920, 1080),
+			want:     []image.Rectangle{image.Rect(1, 1, 3, 3)},
+		},
+		{
+			name:    "image not at the origin",
+			regions: []types.Rectangle{{X: 10, Y: 10, Width: 10, Height: 10}},
+			bounds:  image.Rect(5, 5, 105, 105),
+			want:    []image.Rectangle{image.Rect(15, 15, 25, 25)},
+		},
+		{
+			name: "clipped and dropped",
+			regions: []types.Rectangle{
+				{X: 1900, Y: 1000, Width: 100, Height: 100},
+				{X: 3000, Y: 0, Width: 100, Height: 100},
+			},
+			monitors: []types.Monitor{{Bounds: types.Rectangle{Width: 1920, Height: 1080}}},
+			bounds:   image.Rect(0, 0, 1920, 1080),
+			want:     []image.Rectangle{image.Rect(1900, 1000, 1920, 1080)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := screenToImage(tt.regions, tt.monitors, tt.bounds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("screenToImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSensitiveRegions(t *testing.T) {
+	monitors := []types.Monitor{{Index: 0, Name: "DP-1", Bounds: types.Rectangle{Width: 1920, Height: 1080}}}
+	config := &types.PrivacyConfig{
+		ExcludeApps:        []string{"KeePassXC"},
+		ExcludeTitles:      []string{"incognito"},
+		ExcludeExecutables: []string{"signal-desktop"},
+		BlurSensitive:      true,
+		RedactionRules: []types.RedactionRule{
+			{Detector: types.DetectEmail, Action: types.RedactMask},
+			{Pattern: "diary", Action: types.RedactDrop},
+		},
+	}
+	at := func(x int) types.Rectangle { return types.Rectangle{X: x, Y: 0, Width: 100, Height: 100} }
+
+	tests := []struct {
+		name    string
+		windows []*types.Window
+		want    []types.Rectangle
+		ok      bool
+	}{
+		{
+			name: "hidden windows",
+			windows: []*types.Window{
+				{AppName: "code", Title: "main.go", Position: at(0)},
+				{AppName: "keepassxc", Title: "Passwords", Position: at(100)},
+				{AppName: "firefox", Title: "New Incognito tab", Position: at(200)},
+				{AppName: "Electron", Title: "Signal", Position: at(300),
+					Process: &types.ProcessInfo{Executable: "/opt/Signal/signal-desktop"}},
+				{AppName: "gedit", Title: "diary.txt", Position: at(400)},
+				// Rewritten titles do not make a window sensitive
+				{AppName: "thunderbird", Title: "Inbox - me@example.com", Position: at(500)},
+			},
+			want: []types.Rectangle{at(100), at(200), at(300), at(400)},
+			ok:   true,
+		},
+		{
+			name: "sensitive window without geometry",
+			windows: []*types.Window{
+				{AppName: "KeePassXC", Title: "Passwords"},
+			},
+			ok: false,
+		},
+		{
+			name: "sensitive window covering a monitor",
+			windows: []*types.Window{
+				{AppName: "KeePassXC", Title: "Passwords", Position: types.Rectangle{X: -5, Y: -5, Width: 1930, Height: 1090}},
+			},
+			ok: false,
+		},
+		{
+			name: "other window covering a monitor",
+			windows: []*types.Window{
+				{AppName: "code", Title: "main.go", Position: types.Rectangle{Width: 1920, Height: 1080}},
+			},
+			ok: true,
+		},
+	}
+
+	filter := NewPrivacyFilter(config, []byte("salt"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions, ok := filter.SensitiveRegions(tt.windows, monitors)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(regions, tt.want) {
+				t.Errorf("regions = %v, want %v", regions, tt.want)
+			}
+		})
+	}
+
+	t.Run("blurring disabled", func(t *testing.T) {
+		disabled := *config
+		disabled.BlurSensitive = false
+		regions, ok := NewPrivacyFilter(&disabled, nil).SensitiveRegions(tests[1].windows, monitors)
+		if !ok || regions != nil {
+			t.Errorf("SensitiveRegions() = %v, %v; want nothing to redact", regions, ok)
+		}
+	})
+}
+
+func TestBlurSensitive(t *testing.T) {
+	monitors := []types.Monitor{{Bounds: types.Rectangle{Width: 200, Height: 100}}}
+	filter := NewPrivacyFilter(&types.PrivacyConfig{BlurSensitive: true}, nil)
+	img := stripes(image.Rect(0, 0, 200, 100))
+
+	blurred, err := filter.BlurSensitive(img, []types.Rectangle{{X: 0, Y: 0, Width: 50, Height: 50}}, monitors)
+	if err != nil {
+		t.Fatalf("BlurSensitive: %v", err)
+	}
+	if got := blurred.At(0, 0); got == img.At(0, 0) {
+		t.Error("sensitive region left as is")
+	}
+	if got := blurred.At(100, 0); got != img.At(100, 0) {
+		t.Error("pixel outside the sensitive region changed")
+	}
+
+	// Nothing of a screenshot covered by a sensitive window can be kept
+	if _, err := filter.BlurSensitive(img, []types.Rectangle{{X: -10, Y: -10, Width: 300, Height: 200}}, monitors); err == nil {
+		t.Error("BlurSensitive kept a screenshot covered by a sensitive window")
+	}
+}
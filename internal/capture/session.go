@@ -0,0 +1,146 @@
+package capture
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// Session event kinds
+const (
+	SessionSleep  = "sleep"
+	SessionWake   = "wake"
+	SessionLock   = "lock"
+	SessionUnlock = "unlock"
+)
+
+// SessionEvent reports that the machine is about to sleep, has resumed, or
+// that the session was locked or unlocked
+type SessionEvent struct {
+	Kind      string
+	Timestamp time.Time
+
+	// Release, when set, must be called once the event has been handled;
+	// suspend is delayed until then
+	Release func()
+}
+
+// SessionWatcher is implemented by platform services that report sleep and
+// screen lock transitions
+type SessionWatcher interface {
+	WatchSession(ctx context.Context) (<-chan SessionEvent, error)
+}
+
+// watchSession subscribes to session events, returning nil when the
+// platform cannot provide them
+func (c *CaptureEngine) watchSession(ctx context.Context) <-chan SessionEvent {
+	if c.replay {
+		return nil
+	}
+
+	watcher := newPlatformSessionWatcher()
+	if watcher == nil {
+		return nil
+	}
+
+	events, err := watcher.WatchSession(ctx)
+	if err != nil {
+		log.Printf("Failed to watch sleep and lock events: %v", err)
+		return nil
+	}
+	return events
+}
+
+// handleSessionEvent closes the open activity when the user goes away and
+// records the time spent suspended or locked once they are back
+func (c *CaptureEngine) handleSessionEvent(event SessionEvent) {
+	if event.Release != nil {
+		defer event.Release()
+	}
+
+	c.changeAway(event.Timestamp, "Session "+event.Kind, func() {
+		switch event.Kind {
+		case SessionSleep:
+			c.sleeping = true
+		case SessionWake:
+			c.sleeping = false
+		case SessionLock:
+			c.locked = true
+		case SessionUnlock:
+			c.locked = false
+		}
+	})
+}
+
+// changeAway applies a change of the sleep, lock or pause state made at
+// instant at. The open activity is closed when the user leaves, and each
+// away period is recorded in its category when it ends or changes category.
+func (c *CaptureEngine) changeAway(at time.Time, reason string, change func()) {
+	wasAway := c.awayCategory()
+	if wasAway == "" {
+		// Close the open activity at the moment the user left
+		c.closeActiveTime(at)
+	} else {
+		c.recordGap(wasAway, c.awaySince, at)
+	}
+
+	change()
+
+	if c.awayCategory() != "" {
+		c.awaySince = at
+		log.Printf("%s, pausing capture", reason)
+		return
+	}
+
+	if wasAway != "" {
+		// Restart focus timing from the moment the user came back
+		log.Printf("%s, resuming capture", reason)
+		c.awaySince = time.Time{}
+		c.idleSince = time.Time{}
+		c.lastCapture = at
+		c.segmentStart = at
+		c.lastSnapshot = nil
+	}
+}
+
+// awayCategory returns the category recorded for the current away period,
+// or "" while the user is present
+func (c *CaptureEngine) awayCategory() string {
+	switch {
+	case c.outsideSchedule:
+		// Takes precedence so nothing outside the schedule is recorded
+		return offSchedule
+	case c.sleeping:
+		return types.CategoryAway
+	case c.locked:
+		return types.CategoryLocked
+	case c.paused:
+		return types.CategoryPaused
+	default:
+		return ""
+	}
+}
+
+// closeActiveTime records the focused time up to end before the user leaves
+func (c *CaptureEngine) closeActiveTime(end time.Time) {
+	if !c.idleSince.IsZero() {
+		c.recordGap(types.CategoryIdle, laterOf(c.lastCapture, c.idleSince), end)
+		return
+	}
+
+	if c.lastSnapshot != nil {
+		c.closeSegment(end)
+		return
+	}
+
+	// Polling: attribute the time since the last capture to the window
+	// focused right now. No screenshot is taken; the user is leaving, or
+	// has just asked for tracking to pause.
+	snapshot, err := c.snapshotWorkspace(false)
+	if err != nil || c.lastCapture.IsZero() {
+		return
+	}
+	c.recordActivity(c.segmentToActivity(snapshot, c.lastCapture, end))
+}
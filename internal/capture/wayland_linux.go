@@ -0,0 +1,37 @@
+//go:build linux
+
+package capture
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// detectLinuxBackend picks the window backend for the current session
+func detectLinuxBackend() string {
+	if os.Getenv("XDG_SESSION_TYPE") != "wayland" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return types.BackendX11
+	}
+
+	switch {
+	case os.Getenv("SWAYSOCK") != "":
+		return types.BackendSway
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return types.BackendHyprland
+	}
+
+	// Unknown compositor: only XWayland clients will be visible
+	return types.BackendX11
+}
+
+// grimScreenshot captures all Wayland outputs as PNG using grim
+func grimScreenshot() ([]byte, error) {
+	data, err := exec.Command("grim", "-t", "png", "-").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+	return data, nil
+}
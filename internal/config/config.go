@@ -5,8 +5,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
+	"github.com/faisalahmedsifat/compass/internal/schedule"
 	"github.com/faisalahmedsifat/compass/pkg/types"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -16,6 +18,11 @@ import (
 const (
 	DefaultInterval           = 10 * time.Second
 	DefaultScreenshotInterval = 60 * time.Second // Screenshots every minute by default
+	DefaultIdleThreshold      = 5 * time.Minute
+	DefaultScreenshotQuality  = 75
+	DefaultScreenshotMaxSize  = 1920 // Longest edge in pixels
+	DefaultThumbnailSize      = 320
+	DefaultScreenshotDedup    = 5 // Bits of 64 that may differ
 	DefaultPort               = "8080"
 	DefaultHost               = "localhost"
 	DefaultAutoDeleteDays     = 30
@@ -47,6 +54,15 @@ func Load() (*types.Config, error) {
 		log.Printf("Config file loaded from: %s", viper.ConfigFileUsed())
 	}
 
+	// Configured rules replace the defaults; they would otherwise be decoded
+	// field by field over the default rules
+	if viper.IsSet("privacy.redaction_rules") {
+		config.Privacy.RedactionRules = nil
+	}
+	if viper.IsSet("server.dashboard_origins") {
+		config.Server.DashboardOrigins = nil
+	}
+
 	// Unmarshal into struct
 	if err := viper.Unmarshal(config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
@@ -73,10 +89,19 @@ func Load() (*types.Config, error) {
 func DefaultConfig() *types.Config {
 	return &types.Config{
 		Tracking: &types.TrackingConfig{
-			Interval:           DefaultInterval,
-			ScreenshotInterval: DefaultScreenshotInterval,
-			CaptureScreenshots: true,
-			TrackAllWindows:    true,
+			Interval:                DefaultInterval,
+			ScreenshotInterval:      DefaultScreenshotInterval,
+			CaptureScreenshots:      true,
+			TrackAllWindows:         true,
+			Backend:                 types.BackendAuto,
+			Mode:                    types.ModePoll,
+			IdleThreshold:           DefaultIdleThreshold,
+			SampleResources:         true,
+			ScreenshotFormat:        types.ScreenshotJPEG,
+			ScreenshotQuality:       DefaultScreenshotQuality,
+			ScreenshotMaxSize:       DefaultScreenshotMaxSize,
+			ThumbnailSize:           DefaultThumbnailSize,
+			ScreenshotDedupDistance: DefaultScreenshotDedup,
 		},
 		Privacy: &types.PrivacyConfig{
 			ExcludeApps: []string{
@@ -88,16 +113,31 @@ func DefaultConfig() *types.Config {
 			},
 			ExcludeTitles: []string{
 				"incognito",
-				"private",
+				"private browsing",
+				"inprivate",
 				"password",
 				"secure",
 			},
-			BlurSensitive:  true,
-			AutoDeleteDays: DefaultAutoDeleteDays,
+			RedactionRules: []types.RedactionRule{
+				{Detector: types.DetectEmail, Action: types.RedactMask},
+				{Detector: types.DetectToken, Action: types.RedactMask},
+				{Detector: types.DetectCreditCard, Action: types.RedactMask},
+				{Detector: types.DetectURLQuery, Action: types.RedactMask},
+			},
+			ExcludeIncognito: true,
+			BlurSensitive:    true,
+			AutoDeleteDays:   DefaultAutoDeleteDays,
 		},
 		Server: &types.ServerConfig{
 			Port: DefaultPort,
 			Host: DefaultHost,
+			// The dashboard's development and preview servers
+			DashboardOrigins: []string{
+				"http://localhost:5173",
+				"http://localhost:5174",
+				"http://127.0.0.1:5173",
+				"http://127.0.0.1:5174",
+			},
 		},
 		Storage: &types.StorageConfig{
 			Path:    getDefaultDatabasePath(),
@@ -108,6 +148,9 @@ func DefaultConfig() *types.Config {
 			Provider: "ollama",
 			Model:    "llama2",
 		},
+		Categorization: &types.CategorizationConfig{
+			SplitTime: false,
+		},
 	}
 }
 
@@ -144,6 +187,46 @@ func ValidateConfig(config *types.Config) error {
 		return fmt.Errorf("screenshot interval must be at least 1 second")
 	}
 
+	if config.Tracking.IdleThreshold < 0 {
+		return fmt.Errorf("idle threshold cannot be negative")
+	}
+
+	switch config.Tracking.Backend {
+	case types.BackendAuto, types.BackendX11, types.BackendExec, types.BackendSway, types.BackendHyprland:
+	default:
+		return fmt.Errorf("unknown tracking backend: %s", config.Tracking.Backend)
+	}
+
+	if config.Tracking.Mode != types.ModePoll && config.Tracking.Mode != types.ModeEvents {
+		return fmt.Errorf("unknown tracking mode: %s", config.Tracking.Mode)
+	}
+
+	if config.Tracking.ScreenshotFormat != types.ScreenshotJPEG && config.Tracking.ScreenshotFormat != types.ScreenshotPNG {
+		return fmt.Errorf("unknown screenshot format: %s", config.Tracking.ScreenshotFormat)
+	}
+
+	if config.Tracking.ScreenshotQuality < 1 || config.Tracking.ScreenshotQuality > 100 {
+		return fmt.Errorf("screenshot quality must be between 1 and 100")
+	}
+
+	if config.Tracking.ScreenshotMaxSize < 0 || config.Tracking.ThumbnailSize < 0 {
+		return fmt.Errorf("screenshot sizes cannot be negative")
+	}
+
+	if config.Tracking.ScreenshotDedupDistance > 64 {
+		return fmt.Errorf("screenshot dedup distance cannot exceed 64")
+	}
+
+	if _, err := schedule.New(config.Tracking.Schedule); err != nil {
+		return fmt.Errorf("tracking schedule: %w", err)
+	}
+
+	for i, rule := range config.Privacy.RedactionRules {
+		if err := validateRedactionRule(rule); err != nil {
+			return fmt.Errorf("redaction rule %d: %w", i+1, err)
+		}
+	}
+
 	if config.Privacy.AutoDeleteDays < 1 {
 		return fmt.Errorf("auto delete days must be at least 1")
 	}
@@ -159,6 +242,34 @@ func ValidateConfig(config *types.Config) error {
 	return nil
 }
 
+// validateRedactionRule checks that a redaction rule has one pattern source
+// and a known action
+func validateRedactionRule(rule types.RedactionRule) error {
+	switch {
+	case rule.Pattern != "" && rule.Detector != "":
+		return fmt.Errorf("pattern and detector are mutually exclusive")
+	case rule.Detector != "":
+		switch rule.Detector {
+		case types.DetectEmail, types.DetectToken, types.DetectCreditCard, types.DetectURLQuery:
+		default:
+			return fmt.Errorf("unknown detector: %s", rule.Detector)
+		}
+	case rule.Pattern != "":
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("a pattern or detector is required")
+	}
+
+	switch rule.Action {
+	case types.RedactMask, types.RedactReplace, types.RedactHash, types.RedactDrop:
+	default:
+		return fmt.Errorf("unknown action: %s", rule.Action)
+	}
+	return nil
+}
+
 // getConfigDir returns the configuration directory path
 func getConfigDir() string {
 	homeDir, err := os.UserHomeDir()
@@ -189,6 +300,25 @@ func GetConfigPath() string {
 	return filepath.Join(getConfigDir(), "config.yaml")
 }
 
+// GetRulesPath returns the path to the categorization rules file
+func GetRulesPath() string {
+	return filepath.Join(getConfigDir(), "rules.yaml")
+}
+
+// CreateDefaultRulesFile writes the built-in rules as the rules file if it
+// doesn't exist, so they can be edited
+func CreateDefaultRulesFile(rules []byte) error {
+	rulesPath := GetRulesPath()
+	if _, err := os.Stat(rulesPath); err == nil {
+		return nil // File already exists
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rulesPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(rulesPath, rules, 0644)
+}
+
 // CreateDefaultConfigFile creates a default config file if it doesn't exist
 func CreateDefaultConfigFile() error {
 	configPath := GetConfigPath()
@@ -1,270 +1,156 @@
 package processor
 
 import (
+	"math"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/faisalahmedsifat/compass/pkg/types"
 )
 
 // RuleBasedCategorizer categorizes activities using predefined rules
 type RuleBasedCategorizer struct {
+	mu    sync.RWMutex
 	rules []types.Rule
 }
 
 // NewRuleBasedCategorizer creates a new rule-based categorizer
 func NewRuleBasedCategorizer() *RuleBasedCategorizer {
-	return &RuleBasedCategorizer{
-		rules: createDefaultRules(),
-	}
+	c := &RuleBasedCategorizer{}
+	c.SetRules(createDefaultRules())
+	return c
+}
+
+// SetRules replaces the rules, e.g. with those of a rules file. Rules of
+// equal priority keep their order.
+func (c *RuleBasedCategorizer) SetRules(rules []types.Rule) {
+	sorted := append([]types.Rule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	c.mu.Lock()
+	c.rules = sorted
+	c.mu.Unlock()
+}
+
+// Rules returns the rules in the order they are tried
+func (c *RuleBasedCategorizer) Rules() []types.Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rules
 }
 
 // Categorize categorizes the current workspace based on windows
 func (c *RuleBasedCategorizer) Categorize(windows []types.Window) (string, float64) {
-	if len(windows) == 0 {
-		return "Idle", 1.0
+	snapshot := &types.WorkspaceSnapshot{AllWindows: windows, WindowCount: len(windows)}
+	if active := findActiveWindow(windows); active != nil {
+		snapshot.ActiveWindow = *active
 	}
+	return c.CategorizeSnapshot(snapshot)
+}
 
-	// Sort rules by priority (highest first)
-	sort.Slice(c.rules, func(i, j int) bool {
-		return c.rules[i].Priority > c.rules[j].Priority
-	})
+// CategorizeSnapshot categorizes a workspace snapshot, letting rules match on
+// enricher metadata
+func (c *RuleBasedCategorizer) CategorizeSnapshot(snapshot *types.WorkspaceSnapshot) (string, float64) {
+	category, confidence, _ := c.CategorizeCandidates(snapshot)
+	return category, confidence
+}
+
+// CategorizeCandidates categorizes a workspace snapshot and ranks every
+// category it could belong to. The category is that of the first matching
+// rule. Each candidate is weighted by the priority of its first matching
+// rule, so the candidates share the evidence in proportion to their best
+// rules and the category always ranks first. The confidence is the score of
+// the category.
+func (c *RuleBasedCategorizer) CategorizeCandidates(snapshot *types.WorkspaceSnapshot) (string, float64, []types.CategoryCandidate) {
+	windows := snapshot.AllWindows
+	if len(windows) == 0 {
+		return "Idle", 1.0, []types.CategoryCandidate{{Category: "Idle", Score: 1.0}}
+	}
 
 	// Apply rules in priority order
-	for _, rule := range c.rules {
-		if rule.Matcher(windows) {
-			return rule.Category, 1.0 // High confidence for rule matches
+	// Rules come in priority order, so the first rule matching for a
+	// category is its best and candidates are found best first
+	weights := make(map[string]float64)
+	var order []string
+	total := 0.0
+	for _, rule := range c.Rules() {
+		if _, seen := weights[rule.Category]; seen || !ruleMatches(rule, snapshot) {
+			continue
 		}
+		weight := math.Max(float64(rule.Priority), 1)
+		weights[rule.Category] = weight
+		order = append(order, rule.Category)
+		total += weight
 	}
 
-	// Fallback: try to infer from single app
-	if len(windows) > 0 {
-		activeWindow := findActiveWindow(windows)
-		if activeWindow != nil {
-			category := categorizeByApp(activeWindow.AppName)
-			return category, 0.7 // Lower confidence for fallback
+	if len(order) > 0 {
+		candidates := make([]types.CategoryCandidate, len(order))
+		for i, name := range order {
+			candidates[i] = types.CategoryCandidate{Category: name, Score: roundScore(weights[name] / total)}
 		}
+		return order[0], candidates[0].Score, candidates
+	}
+
+	// Fallback: try to infer from single app
+	if activeWindow := findActiveWindow(windows); activeWindow != nil {
+		category := categorizeByApp(appIdentity(*activeWindow))
+		return category, 0.7, []types.CategoryCandidate{{Category: category, Score: 0.7}} // Lower confidence for fallback
 	}
 
-	return "Uncategorized", 0.5
+	return "Uncategorized", 0.5, []types.CategoryCandidate{{Category: "Uncategorized", Score: 0.5}}
 }
 
-// createDefaultRules creates the default categorization rules
-func createDefaultRules() []types.Rule {
-	return []types.Rule{
-		{
-			Name:     "Development & Testing",
-			Priority: 10,
-			Matcher: func(windows []types.Window) bool {
-				hasIDE := false
-				hasLocalhost := false
-				hasTerminal := false
-
-				for _, w := range windows {
-					if isIDE(w.AppName) {
-						hasIDE = true
-					}
-					if strings.Contains(strings.ToLower(w.Title), "localhost") ||
-						strings.Contains(strings.ToLower(w.Title), "127.0.0.1") {
-						hasLocalhost = true
-					}
-					if isTerminal(w.AppName) {
-						hasTerminal = true
-					}
-				}
-				return hasIDE && (hasLocalhost || hasTerminal)
-			},
-			Category: "Development",
-		},
-		{
-			Name:     "Debugging Session",
-			Priority: 9,
-			Matcher: func(windows []types.Window) bool {
-				hasErrorTerminal := false
-				hasStackOverflow := false
-				hasIDE := false
-
-				for _, w := range windows {
-					title := strings.ToLower(w.Title)
-					if isTerminal(w.AppName) && (strings.Contains(title, "error") ||
-						strings.Contains(title, "exception") ||
-						strings.Contains(title, "failed")) {
-						hasErrorTerminal = true
-					}
-					if strings.Contains(title, "stack overflow") ||
-						strings.Contains(title, "stackoverflow") {
-						hasStackOverflow = true
-					}
-					if isIDE(w.AppName) {
-						hasIDE = true
-					}
-				}
-				return hasErrorTerminal && (hasStackOverflow || hasIDE)
-			},
-			Category: "Debugging",
-		},
-		{
-			Name:     "Code Review",
-			Priority: 8,
-			Matcher: func(windows []types.Window) bool {
-				hasGitHub := false
-				hasIDE := false
-				hasSlack := false
-
-				for _, w := range windows {
-					title := strings.ToLower(w.Title)
-					if strings.Contains(title, "github") ||
-						strings.Contains(title, "pull request") ||
-						strings.Contains(title, "merge request") {
-						hasGitHub = true
-					}
-					if isIDE(w.AppName) {
-						hasIDE = true
-					}
-					if isCommunication(w.AppName) {
-						hasSlack = true
-					}
-				}
-				return hasGitHub && (hasIDE || hasSlack)
-			},
-			Category: "Code Review",
-		},
-		{
-			Name:     "Learning & Documentation",
-			Priority: 7,
-			Matcher: func(windows []types.Window) bool {
-				hasDocumentation := false
-				hasIDE := false
-				hasNotes := false
-
-				for _, w := range windows {
-					title := strings.ToLower(w.Title)
-					app := strings.ToLower(w.AppName)
-
-					if strings.Contains(title, "documentation") ||
-						strings.Contains(title, "docs") ||
-						strings.Contains(title, "tutorial") ||
-						strings.Contains(title, "guide") ||
-						strings.Contains(title, "learn") {
-						hasDocumentation = true
-					}
-					if isIDE(w.AppName) {
-						hasIDE = true
-					}
-					if isNoteTaking(app) {
-						hasNotes = true
-					}
-				}
-				return hasDocumentation && (hasIDE || hasNotes)
-			},
-			Category: "Learning",
-		},
-		{
-			Name:     "Communication & Collaboration",
-			Priority: 6,
-			Matcher: func(windows []types.Window) bool {
-				communicationCount := 0
-				hasWorkContext := false
-
-				for _, w := range windows {
-					if isCommunication(w.AppName) {
-						communicationCount++
-					}
-					if isIDE(w.AppName) || isTerminal(w.AppName) {
-						hasWorkContext = true
-					}
-				}
-				return communicationCount >= 1 && hasWorkContext
-			},
-			Category: "Communication",
-		},
-		{
-			Name:     "Deep Focus",
-			Priority: 5,
-			Matcher: func(windows []types.Window) bool {
-				// Single application focus or minimal distractions
-				workWindows := 0
-				distractionWindows := 0
-
-				for _, w := range windows {
-					if w.IsActive || isWorkRelated(w.AppName) {
-						workWindows++
-					} else if isDistraction(w.AppName) {
-						distractionWindows++
-					}
-				}
-
-				return workWindows >= 1 && distractionWindows == 0 && len(windows) <= 3
-			},
-			Category: "Deep Work",
-		},
-		{
-			Name:     "Research",
-			Priority: 4,
-			Matcher: func(windows []types.Window) bool {
-				browserCount := 0
-				hasResearchKeywords := false
-
-				for _, w := range windows {
-					if isBrowser(w.AppName) {
-						browserCount++
-						title := strings.ToLower(w.Title)
-						if strings.Contains(title, "research") ||
-							strings.Contains(title, "wiki") ||
-							strings.Contains(title, "article") ||
-							strings.Contains(title, "blog") {
-							hasResearchKeywords = true
-						}
-					}
-				}
-				return browserCount >= 1 && (hasResearchKeywords || browserCount >= 3)
-			},
-			Category: "Research",
-		},
-		{
-			Name:     "Meeting & Calls",
-			Priority: 3,
-			Matcher: func(windows []types.Window) bool {
-				for _, w := range windows {
-					app := strings.ToLower(w.AppName)
-					title := strings.ToLower(w.Title)
-
-					if app == "zoom" || app == "microsoft teams" ||
-						app == "google meet" || app == "skype" ||
-						strings.Contains(title, "meeting") ||
-						strings.Contains(title, "zoom") ||
-						strings.Contains(title, "teams") {
-						return true
-					}
-				}
-				return false
-			},
-			Category: "Meetings",
-		},
-		{
-			Name:     "Email & Administration",
-			Priority: 2,
-			Matcher: func(windows []types.Window) bool {
-				for _, w := range windows {
-					app := strings.ToLower(w.AppName)
-					title := strings.ToLower(w.Title)
-
-					if app == "mail" || app == "outlook" || app == "gmail" ||
-						strings.Contains(title, "email") ||
-						strings.Contains(title, "inbox") ||
-						strings.Contains(title, "gmail") {
-						return true
-					}
-				}
-				return false
-			},
-			Category: "Email",
-		},
+// roundScore keeps two decimals of a score
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
+
+// ruleMatches applies a rule, letting rules that understand snapshots see
+// enricher metadata
+func ruleMatches(rule types.Rule, snapshot *types.WorkspaceSnapshot) bool {
+	if rule.SnapshotMatcher != nil {
+		return rule.SnapshotMatcher(snapshot)
 	}
+	return rule.Matcher(snapshot.AllWindows)
 }
 
 // Helper functions to identify application types
 
+// IsDevelopmentTool reports whether a window belongs to an IDE or terminal
+func IsDevelopmentTool(w types.Window) bool {
+	app := appIdentity(w)
+	return isIDE(app) || isTerminal(app)
+}
+
+// IsTerminal reports whether a window belongs to a terminal emulator
+func IsTerminal(w types.Window) bool {
+	return isTerminal(appIdentity(w))
+}
+
+// IsBrowser reports whether a window belongs to a web browser
+func IsBrowser(w types.Window) bool {
+	return isBrowser(appIdentity(w))
+}
+
+// appIdentity returns the name application matchers look at: the app name
+// plus the executable's file name when it differs, so generic hosts such as
+// Electron or java are recognised by what they actually run
+func appIdentity(w types.Window) string {
+	if w.Process == nil || w.Process.Executable == "" {
+		return w.AppName
+	}
+
+	executable := filepath.Base(w.Process.Executable)
+	if strings.EqualFold(executable, w.AppName) {
+		return w.AppName
+	}
+	return w.AppName + " " + executable
+}
+
 func isIDE(appName string) bool {
 	app := strings.ToLower(appName)
 	ides := []string{
@@ -298,6 +184,14 @@ func isTerminal(appName string) bool {
 	return false
 }
 
+// commandName returns the program of a terminal's foreground command
+func commandName(command string) string {
+	if fields := strings.Fields(command); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
 func isBrowser(appName string) bool {
 	app := strings.ToLower(appName)
 	browsers := []string{
@@ -345,29 +239,6 @@ func isNoteTaking(appName string) bool {
 	return false
 }
 
-func isWorkRelated(appName string) bool {
-	return isIDE(appName) || isTerminal(appName) ||
-		strings.Contains(strings.ToLower(appName), "postman") ||
-		strings.Contains(strings.ToLower(appName), "docker") ||
-		strings.Contains(strings.ToLower(appName), "kubernetes")
-}
-
-func isDistraction(appName string) bool {
-	app := strings.ToLower(appName)
-	distractions := []string{
-		"youtube", "netflix", "tiktok", "instagram",
-		"facebook", "twitter", "reddit", "twitch",
-		"spotify", "music", "games", "steam",
-	}
-
-	for _, distraction := range distractions {
-		if strings.Contains(app, distraction) {
-			return true
-		}
-	}
-	return false
-}
-
 // categorizeByApp provides fallback categorization based on single app
 func categorizeByApp(appName string) string {
 	app := strings.ToLower(appName)
@@ -415,6 +286,7 @@ func GetCategoryDescription(category string) string {
 	descriptions := map[string]string{
 		"Development":   "Writing, testing, or debugging code",
 		"Debugging":     "Investigating and fixing errors",
+		"Database":      "Querying and administering databases",
 		"Code Review":   "Reviewing code changes and collaborating",
 		"Learning":      "Reading documentation, tutorials, or studying",
 		"Communication": "Team collaboration and messaging",
@@ -427,7 +299,7 @@ func GetCategoryDescription(category string) string {
 		"Entertainment": "Non-work activities and entertainment",
 		"General":       "General computer usage",
 		"Uncategorized": "Activity pattern not recognized",
-		"Idle":          "No active windows detected",
+		"Idle":          "Away from the keyboard or no active windows",
 	}
 
 	if desc, exists := descriptions[category]; exists {
@@ -441,6 +313,7 @@ func GetCategoryColor(category string) string {
 	colors := map[string]string{
 		"Development":   "#28a745", // Green
 		"Debugging":     "#dc3545", // Red
+		"Database":      "#795548", // Brown
 		"Code Review":   "#17a2b8", // Cyan
 		"Learning":      "#6f42c1", // Purple
 		"Communication": "#fd7e14", // Orange
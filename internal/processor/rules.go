@@ -0,0 +1,772 @@
+package processor
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultRules is the rules file shipped with Compass
+//
+//go:embed default_rules.yaml
+var defaultRules []byte
+
+// DefaultRules returns the built-in rules file, for writing it out as a
+// starting point for user rules
+func DefaultRules() []byte {
+	return defaultRules
+}
+
+// createDefaultRules compiles the built-in rules
+func createDefaultRules() []types.Rule {
+	rules, err := ParseRules(defaultRules, "default_rules.yaml")
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in rules: %v", err))
+	}
+	return rules
+}
+
+// LoadRules reads and compiles a rules file
+func LoadRules(path string) ([]types.Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRules(data, path)
+}
+
+// ParseRules compiles a rules file. Every problem found is reported as
+// "filename:line: message".
+func ParseRules(data []byte, filename string) ([]types.Rule, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("%s: no rules", filename)
+	}
+
+	p := &ruleParser{
+		filename: filename,
+		sets:     make(map[string]*yaml.Node),
+		resolved: make(map[string][]string),
+	}
+	rules := p.parseFile(document.Content[0])
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	return rules, nil
+}
+
+// windowCondition tests one window
+type windowCondition func(w *types.Window) bool
+
+// workspaceCondition tests a whole snapshot
+type workspaceCondition func(snapshot *types.WorkspaceSnapshot) bool
+
+// explanation is a compiled workspace condition with the source it came
+// from, for explaining why a rule matches or not
+type explanation struct {
+	line      int
+	source    string
+	condition workspaceCondition
+	parts     []*explanation
+}
+
+// explain appends a line per condition telling whether it holds
+func (e *explanation) explain(snapshot *types.WorkspaceSnapshot, indent string, lines []string) []string {
+	mark := "✗"
+	if e.condition(snapshot) {
+		mark = "✓"
+	}
+	lines = append(lines, fmt.Sprintf("%s%s line %d: %s", indent, mark, e.line, e.source))
+	for _, part := range e.parts {
+		lines = part.explain(snapshot, indent+"  ", lines)
+	}
+	return lines
+}
+
+// ruleParser compiles the YAML nodes of a rules file, collecting errors so
+// all of them are reported at once
+type ruleParser struct {
+	filename string
+	sets     map[string]*yaml.Node
+	resolved map[string][]string
+	errs     []error
+}
+
+// errorf records an error at a node's line
+func (p *ruleParser) errorf(node *yaml.Node, format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf("%s:%d: %s", p.filename, node.Line, fmt.Sprintf(format, args...)))
+}
+
+// parseFile compiles the top level: sets first, as rules refer to them
+func (p *ruleParser) parseFile(node *yaml.Node) []types.Rule {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "expected a mapping with sets and rules")
+		return nil
+	}
+
+	var rulesNode *yaml.Node
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "sets":
+			p.parseSets(value)
+		case "rules":
+			rulesNode = value
+		default:
+			p.errorf(key, "unknown key %q, expected sets or rules", key.Value)
+		}
+	}
+
+	if rulesNode == nil {
+		p.errorf(node, "no rules")
+		return nil
+	}
+	return p.parseRules(rulesNode)
+}
+
+// parseSets records the named lists that conditions refer to with $name
+func (p *ruleParser) parseSets(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "sets must map names to lists")
+		return
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.SequenceNode {
+			p.errorf(value, "set %q must be a list", key.Value)
+			continue
+		}
+		p.sets[key.Value] = value
+	}
+}
+
+// parseRules compiles the list of rules
+func (p *ruleParser) parseRules(node *yaml.Node) []types.Rule {
+	if node.Kind != yaml.SequenceNode {
+		p.errorf(node, "rules must be a list")
+		return nil
+	}
+
+	names := make(map[string]int)
+	rules := make([]types.Rule, 0, len(node.Content))
+	for _, ruleNode := range node.Content {
+		rule, ok := p.parseRule(ruleNode)
+		if line, exists := names[rule.Name]; exists && rule.Name != "" {
+			p.errorf(ruleNode, "rule %q is already defined on line %d", rule.Name, line)
+			continue
+		}
+		names[rule.Name] = ruleNode.Line
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseRule compiles one rule
+func (p *ruleParser) parseRule(node *yaml.Node) (types.Rule, bool) {
+	var rule types.Rule
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "a rule must be a mapping with name, priority, category and when")
+		return rule, false
+	}
+
+	errs := len(p.errs)
+	var when workspaceCondition
+	var explanations []*explanation
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "name":
+			rule.Name = p.parseString(value)
+		case "category":
+			rule.Category = p.parseString(value)
+		case "priority":
+			rule.Priority = p.parseInt(value)
+		case "when":
+			when, explanations = p.parseWorkspaceCondition(value)
+		default:
+			p.errorf(key, "unknown rule key %q", key.Value)
+		}
+	}
+
+	if rule.Name == "" {
+		p.errorf(node, "rule has no name")
+	}
+	if rule.Category == "" {
+		p.errorf(node, "rule %q has no category", rule.Name)
+	}
+	if when == nil && len(p.errs) == errs {
+		p.errorf(node, "rule %q has no when condition", rule.Name)
+	}
+	if len(p.errs) > errs {
+		return rule, false
+	}
+
+	rule.SnapshotMatcher = when
+	rule.Explain = func(snapshot *types.WorkspaceSnapshot) []string {
+		var lines []string
+		for _, e := range explanations {
+			lines = e.explain(snapshot, "", lines)
+		}
+		return lines
+	}
+	return rule, true
+}
+
+// parseWorkspaceCondition compiles a condition on the workspace. All keys of
+// the mapping must hold. Each key is explained on its own.
+func (p *ruleParser) parseWorkspaceCondition(node *yaml.Node) (workspaceCondition, []*explanation) {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		p.errorf(node, "expected a condition")
+		return nil, nil
+	}
+
+	var conditions []workspaceCondition
+	var explanations []*explanation
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var condition workspaceCondition
+		var parts []*explanation
+		switch key.Value {
+		case "all", "any", "none":
+			var combined []workspaceCondition
+			for _, part := range p.parseList(value) {
+				partCondition, partExplanations := p.parseWorkspaceCondition(part)
+				combined = append(combined, partCondition)
+				if len(partExplanations) == 1 {
+					parts = append(parts, partExplanations...)
+				} else {
+					parts = append(parts, &explanation{line: part.Line, source: "all of", condition: partCondition, parts: partExplanations})
+				}
+			}
+			condition = combine(key.Value, combined)
+		case "focused":
+			if match := p.parseWindowCondition(value); match != nil {
+				condition = func(snapshot *types.WorkspaceSnapshot) bool {
+					focused := findActiveWindow(snapshot.AllWindows)
+					return focused != nil && match(focused)
+				}
+			}
+		case "any_window":
+			if match := p.parseWindowCondition(value); match != nil {
+				condition = func(snapshot *types.WorkspaceSnapshot) bool {
+					return countWindows(snapshot, match) > 0
+				}
+			}
+		case "windows":
+			condition = p.parseWindowCount(value)
+		case "window_count":
+			if min, max, ok := p.parseRange(value, nil); ok {
+				condition = func(snapshot *types.WorkspaceSnapshot) bool {
+					return inRange(len(snapshot.AllWindows), min, max)
+				}
+			}
+		case "repository_in", "repository_contains":
+			match := p.parseNameMatcher(key.Value, value)
+			condition = func(snapshot *types.WorkspaceSnapshot) bool {
+				repo := snapshot.Repository
+				return repo != nil && match(filepath.Base(repo.Root), repo.Remote)
+			}
+		case "branch_matches":
+			if pattern := p.parsePattern(key.Value, value); pattern != nil {
+				condition = func(snapshot *types.WorkspaceSnapshot) bool {
+					return snapshot.Repository != nil && pattern.MatchString(snapshot.Repository.Branch)
+				}
+			}
+		case "tab_host":
+			hosts := p.parseNames(value)
+			condition = func(snapshot *types.WorkspaceSnapshot) bool {
+				return snapshot.Tab != nil && matchHost(snapshot.Tab.Domain(), hosts)
+			}
+		case "url_matches":
+			if pattern := p.parsePattern(key.Value, value); pattern != nil {
+				condition = func(snapshot *types.WorkspaceSnapshot) bool {
+					return snapshot.Tab != nil && pattern.MatchString(snapshot.Tab.URL)
+				}
+			}
+		case "metadata":
+			condition = p.parseMetadataCondition(value)
+		default:
+			p.errorf(key, "unknown condition %q", key.Value)
+		}
+		conditions = append(conditions, condition)
+
+		source := key.Value + " of"
+		if parts == nil {
+			source = key.Value + ": " + flowSource(value)
+		}
+		explanations = append(explanations, &explanation{line: key.Line, source: source, condition: condition, parts: parts})
+	}
+	return combine("all", conditions), explanations
+}
+
+// flowSource renders a condition on one line as it was written, so $set
+// references stay short
+func flowSource(node *yaml.Node) string {
+	data, err := yaml.Marshal(flowNode(node))
+	if err != nil {
+		return node.Value
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// flowNode copies a node without comments in flow style
+func flowNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.HeadComment, copied.LineComment, copied.FootComment = "", "", ""
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		copied.Style = yaml.FlowStyle
+	}
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = flowNode(child)
+	}
+	return &copied
+}
+
+// parseWindowCount compiles {match: <window condition>, min: N, max: N}
+func (p *ruleParser) parseWindowCount(node *yaml.Node) workspaceCondition {
+	var match windowCondition
+	hasMatch := false
+	min, max, ok := p.parseRange(node, func(key, value *yaml.Node) bool {
+		if key.Value != "match" {
+			return false
+		}
+		match, hasMatch = p.parseWindowCondition(value), true
+		return true
+	})
+	if ok && !hasMatch {
+		p.errorf(node, "windows needs a match condition")
+	}
+	if !ok || match == nil {
+		return nil
+	}
+
+	return func(snapshot *types.WorkspaceSnapshot) bool {
+		return inRange(countWindows(snapshot, match), min, max)
+	}
+}
+
+// parseRange compiles a mapping with min and max, handing other keys to
+// extra. A missing max is returned as -1.
+func (p *ruleParser) parseRange(node *yaml.Node, extra func(key, value *yaml.Node) bool) (int, int, bool) {
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "expected a mapping with min and max")
+		return 0, 0, false
+	}
+
+	errs := len(p.errs)
+	min, max, bounded := 0, -1, false
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case key.Value == "min":
+			min, bounded = p.parseInt(value), true
+		case key.Value == "max":
+			max, bounded = p.parseInt(value), true
+		case extra != nil && extra(key, value):
+		default:
+			p.errorf(key, "unknown key %q, expected min or max", key.Value)
+		}
+	}
+
+	switch {
+	case !bounded:
+		p.errorf(node, "expected min or max")
+	case min < 0 || max < -1:
+		p.errorf(node, "min and max cannot be negative")
+	case max >= 0 && min > max:
+		p.errorf(node, "min %d is greater than max %d", min, max)
+	}
+	return min, max, len(p.errs) == errs
+}
+
+// parseWindowCondition compiles a condition on one window. All keys of the
+// mapping must hold.
+func (p *ruleParser) parseWindowCondition(node *yaml.Node) windowCondition {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		p.errorf(node, "expected a window condition")
+		return nil
+	}
+
+	var conditions []windowCondition
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var condition windowCondition
+		switch key.Value {
+		case "all", "any", "none":
+			var parts []windowCondition
+			for _, part := range p.parseList(value) {
+				parts = append(parts, p.parseWindowCondition(part))
+			}
+			condition = combine(key.Value, parts)
+		case "app_in", "app_contains", "command_in", "command_contains":
+			condition = p.parseNameCondition(key.Value, value)
+		case "title_matches":
+			if pattern := p.parsePattern(key.Value, value); pattern != nil {
+				condition = func(w *types.Window) bool {
+					return pattern.MatchString(w.Title)
+				}
+			}
+		case "connected_to_port":
+			ports := make(map[int]bool)
+			for _, name := range p.parseNames(value) {
+				port, err := strconv.Atoi(name)
+				if err != nil || port < 1 || port > 65535 {
+					p.errorf(value, "invalid port %q", name)
+					continue
+				}
+				ports[port] = true
+			}
+			condition = func(w *types.Window) bool {
+				for _, connection := range w.Connections {
+					if ports[connection.RemotePort] {
+						return true
+					}
+				}
+				return false
+			}
+		case "connected_to_host":
+			condition = p.parseHostCondition(value)
+		case "focused":
+			var focused bool
+			if err := value.Decode(&focused); err != nil {
+				p.errorf(value, "focused must be true or false")
+				break
+			}
+			condition = func(w *types.Window) bool {
+				return w.IsActive == focused
+			}
+		default:
+			p.errorf(key, "unknown window condition %q", key.Value)
+		}
+		conditions = append(conditions, condition)
+	}
+	return combine("all", conditions)
+}
+
+// hostEndpoint is a host, and a port unless it is 0, that connections are
+// matched against
+type hostEndpoint struct {
+	host string
+	port int
+}
+
+// parseHostCondition compiles connected_to_host. Each item is a host or
+// host:port; the host is compared ignoring case with the name the hosts file
+// gives the remote address, or with the address itself.
+func (p *ruleParser) parseHostCondition(node *yaml.Node) windowCondition {
+	var endpoints []hostEndpoint
+	for _, name := range p.parseNames(node) {
+		endpoint := hostEndpoint{host: strings.Trim(name, "[]")}
+		if host, port, err := net.SplitHostPort(name); err == nil {
+			endpoint.host = host
+			if endpoint.port, err = strconv.Atoi(port); err != nil || endpoint.port < 1 || endpoint.port > 65535 {
+				p.errorf(node, "invalid port in host %q", name)
+				continue
+			}
+		}
+		if endpoint.host == "" {
+			p.errorf(node, "invalid host %q", name)
+			continue
+		}
+		endpoint.host = strings.ToLower(endpoint.host)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return func(w *types.Window) bool {
+		for _, connection := range w.Connections {
+			for _, endpoint := range endpoints {
+				if endpoint.port != 0 && endpoint.port != connection.RemotePort {
+					continue
+				}
+				if strings.EqualFold(connection.Host, endpoint.host) || connection.RemoteIP == endpoint.host {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// parseNameCondition compiles app_in, app_contains, command_in and
+// command_contains. Applications are matched by their name and by their
+// executable, each on its own.
+func (p *ruleParser) parseNameCondition(kind string, node *yaml.Node) windowCondition {
+	match := p.parseNameMatcher(kind, node)
+	if strings.HasPrefix(kind, "command") {
+		return func(w *types.Window) bool { return match(commandName(w.Command)) }
+	}
+	return func(w *types.Window) bool { return match(appNames(w)...) }
+}
+
+// parseNameMatcher compiles the names of an _in or _contains condition into
+// a test of whether any of the values is, or contains, one of the names.
+// Names are compared ignoring case.
+func (p *ruleParser) parseNameMatcher(kind string, node *yaml.Node) func(values ...string) bool {
+	names := p.parseNames(node)
+	for i := range names {
+		names[i] = strings.ToLower(names[i])
+	}
+
+	exact := strings.HasSuffix(kind, "_in")
+	return func(values ...string) bool {
+		for _, value := range values {
+			if value == "" {
+				continue
+			}
+			value = strings.ToLower(value)
+			for _, name := range names {
+				if value == name || !exact && strings.Contains(value, name) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// parsePattern compiles the regular expression of a _matches condition,
+// ignoring case
+func (p *ruleParser) parsePattern(kind string, node *yaml.Node) *regexp.Regexp {
+	pattern, err := regexp.Compile("(?i)" + p.parseString(node))
+	if err != nil {
+		p.errorf(node, "invalid %s pattern: %v", kind, err)
+		return nil
+	}
+	return pattern
+}
+
+// matchHost reports whether a host is one of hosts or a subdomain of one,
+// ignoring case
+func matchHost(host string, hosts []string) bool {
+	if host == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	for _, name := range hosts {
+		name = strings.ToLower(strings.TrimPrefix(name, "www."))
+		if host == name || strings.HasSuffix(host, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMetadataCondition compiles metadata: a mapping of metadata keys to
+// patterns that their values must match. A key may reach into a value with
+// dots, e.g. resources.cpu_percent.
+func (p *ruleParser) parseMetadataCondition(node *yaml.Node) workspaceCondition {
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		p.errorf(node, "metadata must map keys to patterns")
+		return nil
+	}
+
+	type metadataPattern struct {
+		path    []string
+		pattern *regexp.Regexp
+	}
+	var patterns []metadataPattern
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		pattern := p.parsePattern("metadata", value)
+		if pattern == nil {
+			continue
+		}
+		patterns = append(patterns, metadataPattern{path: strings.Split(key.Value, "."), pattern: pattern})
+	}
+	if len(patterns) < len(node.Content)/2 {
+		return nil
+	}
+
+	return func(snapshot *types.WorkspaceSnapshot) bool {
+		for _, pattern := range patterns {
+			value, ok := metadataValue(snapshot.Metadata, pattern.path)
+			if !ok || !pattern.pattern.MatchString(value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// metadataValue renders the metadata value at a path as text: strings as
+// they are, other values as JSON. Values are read in their JSON form so
+// metadata fresh from enrichers and metadata read back from storage match
+// alike.
+func metadataValue(metadata types.Metadata, path []string) (string, bool) {
+	value, ok := metadata[path[0]]
+	if !ok {
+		return "", false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return "", false
+	}
+	for _, key := range path[1:] {
+		fields, ok := generic.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if generic, ok = fields[key]; !ok {
+			return "", false
+		}
+	}
+
+	if text, ok := generic.(string); ok {
+		return text, true
+	}
+	data, err = json.Marshal(generic)
+	return string(data), err == nil
+}
+
+// appNames returns the names a window's application is known by: its name
+// and, when it differs, the base name of its executable
+func appNames(w *types.Window) []string {
+	if w.Process == nil || w.Process.Executable == "" {
+		return []string{w.AppName}
+	}
+	executable := filepath.Base(w.Process.Executable)
+	if strings.EqualFold(executable, w.AppName) {
+		return []string{w.AppName}
+	}
+	return []string{w.AppName, executable}
+}
+
+// parseNames reads a name or a list of names, expanding $set references
+func (p *ruleParser) parseNames(node *yaml.Node) []string {
+	var names []string
+	for _, item := range p.parseList(node) {
+		names = append(names, p.expand(item, nil)...)
+	}
+	return names
+}
+
+// expand returns a name, or the names of the set it refers to. visiting
+// holds the sets being expanded to catch sets that contain themselves.
+func (p *ruleParser) expand(node *yaml.Node, visiting map[string]bool) []string {
+	value := p.parseString(node)
+	if !strings.HasPrefix(value, "$") {
+		return []string{value}
+	}
+
+	name := strings.TrimPrefix(value, "$")
+	if names, ok := p.resolved[name]; ok {
+		return names
+	}
+	set, ok := p.sets[name]
+	if !ok {
+		p.errorf(node, "unknown set %q", name)
+		return nil
+	}
+	if visiting[name] {
+		p.errorf(node, "set %q contains itself", name)
+		return nil
+	}
+
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	visiting[name] = true
+	var names []string
+	for _, item := range set.Content {
+		names = append(names, p.expand(item, visiting)...)
+	}
+	delete(visiting, name)
+
+	p.resolved[name] = names
+	return names
+}
+
+// parseList returns the items of a list, or a single item on its own
+func (p *ruleParser) parseList(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.SequenceNode {
+		if len(node.Content) == 0 {
+			p.errorf(node, "empty list")
+		}
+		return node.Content
+	}
+	return []*yaml.Node{node}
+}
+
+// parseString reads a scalar
+func (p *ruleParser) parseString(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a single value")
+		return ""
+	}
+	return node.Value
+}
+
+// parseInt reads an integer
+func (p *ruleParser) parseInt(node *yaml.Node) int {
+	var value int
+	if node.Kind != yaml.ScalarNode || node.Decode(&value) != nil {
+		p.errorf(node, "expected a number")
+	}
+	return value
+}
+
+// combine joins conditions with all, any or none. It returns nil when a
+// part failed to compile; the error has been recorded.
+func combine[T ~func(*U) bool, U any](kind string, parts []T) T {
+	for _, part := range parts {
+		if part == nil {
+			return nil
+		}
+	}
+	if kind == "all" && len(parts) == 1 {
+		return parts[0]
+	}
+
+	return T(func(value *U) bool {
+		for _, part := range parts {
+			if part(value) {
+				switch kind {
+				case "any":
+					return true
+				case "none":
+					return false
+				}
+			} else if kind == "all" {
+				return false
+			}
+		}
+		return kind != "any"
+	})
+}
+
+// countWindows counts the windows of a snapshot matching a condition
+func countWindows(snapshot *types.WorkspaceSnapshot, match windowCondition) int {
+	count := 0
+	for i := range snapshot.AllWindows {
+		if match(&snapshot.AllWindows[i]) {
+			count++
+		}
+	}
+	return count
+}
+
+// inRange reports whether n is within min and max, -1 meaning no maximum
+func inRange(n, min, max int) bool {
+	return n >= min && (max < 0 || n <= max)
+}
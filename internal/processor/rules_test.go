@@ -0,0 +1,212 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// window returns a focused window of an application started from executable
+func window(appName, executable, title string) types.Window {
+	w := types.Window{AppName: appName, Title: title, IsActive: true}
+	if executable != "" {
+		w.Process = &types.ProcessInfo{Executable: executable}
+	}
+	return w
+}
+
+func TestDefaultRulesMatchAppsByNameOrExecutable(t *testing.T) {
+	tests := []struct {
+		name   string
+		window types.Window
+		want   string // "" when neither Meetings nor Email is a candidate
+	}{
+		{"name", window("zoom", "", "Standup"), "Meetings"},
+		{"name with another executable", window("Zoom", "/opt/zoom/zoom.real", "Standup"), "Meetings"},
+		{"executable with another name", window("Electron", "/usr/bin/outlook", "Calendar"), "Email"},
+		{"name of another app", window("Zoomer", "/usr/bin/zoomer", "Standup"), ""},
+	}
+
+	c := NewRuleBasedCategorizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := &types.WorkspaceSnapshot{AllWindows: []types.Window{tt.window}}
+			_, _, candidates := c.CategorizeCandidates(snapshot)
+
+			got := ""
+			for _, candidate := range candidates {
+				if candidate.Category == "Meetings" || candidate.Category == "Email" {
+					got = candidate.Category
+				}
+			}
+			if got != tt.want {
+				t.Errorf("candidates = %v, want %q among them", candidates, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppInMatchesNameAndExecutableSeparately(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - name: Chat
+    priority: 1
+    category: Communication
+    when:
+      focused: {app_in: [signal, slack]}
+`), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	match := rules[0].SnapshotMatcher
+
+	tests := []struct {
+		window types.Window
+		want   bool
+	}{
+		{window("Slack", "/usr/lib/slack/slack", "general"), true},
+		{window("Electron", "/opt/Signal/signal", "Signal"), true},
+		{window("Signal", "/opt/Signal/signal-desktop", "Signal"), true},
+		{window("Signal", "/usr/bin/slack", ""), true},
+		// Only whole names match
+		{window("Electron", "/opt/Signal/signal-desktop", "Signal"), false},
+		{window("signal slack", "", ""), false},
+	}
+
+	for _, tt := range tests {
+		snapshot := &types.WorkspaceSnapshot{AllWindows: []types.Window{tt.window}}
+		if got := match(snapshot); got != tt.want {
+			t.Errorf("app_in on %s (%v) = %v, want %v", tt.window.AppName, tt.window.Process, got, tt.want)
+		}
+	}
+}
+
+func TestConnectedToHost(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - name: Staging
+    priority: 1
+    category: Operations
+    when:
+      any_window: {connected_to_host: [staging, "10.0.0.5:22", "[::1]:5432"]}
+`), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	match := rules[0].SnapshotMatcher
+
+	tests := []struct {
+		name       string
+		connection types.Connection
+		want       bool
+	}{
+		{"host name on any port", types.Connection{RemoteIP: "10.0.0.9", RemotePort: 443, Host: "Staging"}, true},
+		{"address and port", types.Connection{RemoteIP: "10.0.0.5", RemotePort: 22}, true},
+		{"address on another port", types.Connection{RemoteIP: "10.0.0.5", RemotePort: 80}, false},
+		{"IPv6 address and port", types.Connection{RemoteIP: "::1", RemotePort: 5432, Host: "localhost"}, true},
+		{"other host", types.Connection{RemoteIP: "10.0.0.9", RemotePort: 443, Host: "production"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := window("ssh", "", "")
+			w.Connections = []types.Connection{tt.connection}
+			snapshot := &types.WorkspaceSnapshot{AllWindows: []types.Window{w}}
+			if got := match(snapshot); got != tt.want {
+				t.Errorf("connected_to_host = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseRules([]byte(`
+rules:
+  - name: Bad
+    priority: 1
+    category: Operations
+    when:
+      any_window: {connected_to_host: ["db:99999"]}
+`), "rules.yaml"); err == nil {
+		t.Error("ParseRules accepted an invalid port")
+	}
+}
+
+func TestEnricherConditions(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+rules:
+  - name: Repository
+    priority: 1
+    category: Development
+    when: {repository_in: [compass], branch_matches: '^feature/'}
+  - name: Remote
+    priority: 1
+    category: Development
+    when: {repository_contains: [github.com/faisalahmedsifat]}
+  - name: Docs
+    priority: 1
+    category: Research
+    when: {tab_host: [go.dev], url_matches: '/doc/'}
+  - name: Busy
+    priority: 1
+    category: Development
+    when: {metadata: {resources.processes: '^[0-9]{3,}$', resources.app_name: code}}
+`), "rules.yaml")
+	if err != nil {
+		t.Fatalf("ParseRules: %v", err)
+	}
+	matches := func(name string, snapshot *types.WorkspaceSnapshot) bool {
+		for _, rule := range rules {
+			if rule.Name == name {
+				return rule.SnapshotMatcher(snapshot)
+			}
+		}
+		t.Fatalf("no rule %q", name)
+		return false
+	}
+
+	repository := &types.Repository{Root: "/home/me/src/compass", Remote: "git@github.com:faisalahmedsifat/compass.git", Branch: "feature/rules"}
+	tab := &types.BrowserTab{URL: "https://pkg.go.dev/doc/install?x=1"}
+	sample := types.ResourceSample{AppName: "code", Processes: 120}
+
+	tests := []struct {
+		rule     string
+		snapshot *types.WorkspaceSnapshot
+		want     bool
+	}{
+		{"Repository", &types.WorkspaceSnapshot{Repository: repository}, true},
+		{"Repository", &types.WorkspaceSnapshot{Repository: &types.Repository{Root: "/src/compass", Branch: "main"}}, false},
+		{"Repository", &types.WorkspaceSnapshot{}, false},
+		{"Remote", &types.WorkspaceSnapshot{Repository: repository}, false},
+		{"Remote", &types.WorkspaceSnapshot{Repository: &types.Repository{Root: "/src/x", Remote: "https://github.com/faisalahmedsifat/x"}}, true},
+		{"Docs", &types.WorkspaceSnapshot{Tab: tab}, true},
+		{"Docs", &types.WorkspaceSnapshot{Tab: &types.BrowserTab{URL: "https://notgo.dev/doc/"}}, false},
+		{"Docs", &types.WorkspaceSnapshot{}, false},
+		{"Busy", &types.WorkspaceSnapshot{Metadata: types.Metadata{"resources": sample}}, true},
+		// Metadata read back from storage is generic JSON
+		{"Busy", &types.WorkspaceSnapshot{Metadata: types.Metadata{"resources": map[string]interface{}{"app_name": "code", "processes": 120.0}}}, true},
+		{"Busy", &types.WorkspaceSnapshot{Metadata: types.Metadata{"resources": types.ResourceSample{AppName: "code", Processes: 12}}}, false},
+		{"Busy", &types.WorkspaceSnapshot{}, false},
+	}
+
+	for i, tt := range tests {
+		if got := matches(tt.rule, tt.snapshot); got != tt.want {
+			t.Errorf("%d: rule %s = %v, want %v", i, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestRulesTestKeepsManualLabels(t *testing.T) {
+	zoom := window("Zoom", "", "Standup")
+	activities := []*types.Activity{
+		{IsActive: true, Category: "Email", FocusDuration: 60, AllWindows: []types.Window{zoom}},
+		{IsActive: true, Category: "Email", FocusDuration: 60, AllWindows: []types.Window{zoom}, Manual: true},
+		{IsActive: false, Category: "Idle", FocusDuration: 60},
+	}
+
+	result := NewRuleBasedCategorizer().Test(activities)
+	if result.Activities != 1 || result.Manual != 1 {
+		t.Errorf("tested %d activities and kept %d manual ones, want 1 and 1", result.Activities, result.Manual)
+	}
+	if len(result.Flips) != 1 || result.Flips[0].Activity != activities[0] {
+		t.Errorf("flips = %v, want only the activity categorized by rules", result.Flips)
+	}
+}
@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// RuleTestResult compares the stored categories of activities with those a
+// categorizer assigns them now
+type RuleTestResult struct {
+	Activities int
+	Manual     int // Activities labelled by hand, which rules do not change
+	Before     map[string]time.Duration // Time per stored category
+	After      map[string]time.Duration // Time per new category
+	// Confusion counts activities by stored and new category
+	Confusion map[string]map[string]int
+	Flips     []CategoryFlip // Activities whose category changes, oldest first
+}
+
+// CategoryFlip is an activity the rules categorize differently
+type CategoryFlip struct {
+	Activity *types.Activity
+	From     string
+	To       string
+}
+
+// ActivitySnapshot rebuilds the snapshot an activity was categorized from
+func ActivitySnapshot(activity *types.Activity) *types.WorkspaceSnapshot {
+	snapshot := &types.WorkspaceSnapshot{
+		Timestamp:   activity.Timestamp,
+		AllWindows:  activity.AllWindows,
+		WindowCount: len(activity.AllWindows),
+		Monitors:    activity.Monitors,
+		Repository:  activity.Repository,
+		Tab:         activity.Tab,
+		Metadata:    activity.Metadata,
+	}
+	if active := findActiveWindow(activity.AllWindows); active != nil {
+		snapshot.ActiveWindow = *active
+	}
+	return snapshot
+}
+
+// Test categorizes stored activities again. Idle, away, locked and paused
+// time is not categorized by rules and is left out; activities labelled by
+// hand keep their category and are only counted.
+func (c *RuleBasedCategorizer) Test(activities []*types.Activity) *RuleTestResult {
+	result := &RuleTestResult{
+		Before:    make(map[string]time.Duration),
+		After:     make(map[string]time.Duration),
+		Confusion: make(map[string]map[string]int),
+	}
+
+	for i := len(activities) - 1; i >= 0; i-- {
+		activity := activities[i]
+		if !activity.IsActive {
+			continue
+		}
+		if activity.Manual {
+			result.Manual++
+			continue
+		}
+
+		category, _ := c.CategorizeSnapshot(ActivitySnapshot(activity))
+		duration := time.Duration(activity.FocusDuration) * time.Second
+		result.Activities++
+		result.Before[activity.Category] += duration
+		result.After[category] += duration
+
+		if result.Confusion[activity.Category] == nil {
+			result.Confusion[activity.Category] = make(map[string]int)
+		}
+		result.Confusion[activity.Category][category]++
+
+		if category != activity.Category {
+			result.Flips = append(result.Flips, CategoryFlip{Activity: activity, From: activity.Category, To: category})
+		}
+	}
+	return result
+}
+
+// Explain describes how a snapshot is categorized: the conditions of every
+// rule tried, up to the one that matched
+func (c *RuleBasedCategorizer) Explain(snapshot *types.WorkspaceSnapshot) string {
+	var b strings.Builder
+	if len(snapshot.AllWindows) == 0 {
+		b.WriteString("No windows: Idle\n")
+		return b.String()
+	}
+
+	ruleMatched := false
+	for _, rule := range c.Rules() {
+		matched := ruleMatches(rule, snapshot)
+		outcome := "does not match"
+		if matched {
+			outcome = "matches"
+		}
+		fmt.Fprintf(&b, "%s (priority %d, %s) %s\n", rule.Name, rule.Priority, rule.Category, outcome)
+		if rule.Explain != nil {
+			for _, line := range rule.Explain(snapshot) {
+				fmt.Fprintf(&b, "    %s\n", line)
+			}
+		}
+		if matched {
+			ruleMatched = true
+			break
+		}
+	}
+
+	category, confidence, candidates := c.CategorizeCandidates(snapshot)
+	if !ruleMatched {
+		if active := findActiveWindow(snapshot.AllWindows); active != nil {
+			fmt.Fprintf(&b, "No rule matches, categorized by the focused application %q\n", active.AppName)
+		} else {
+			b.WriteString("No rule matches and no window is focused\n")
+		}
+	}
+	fmt.Fprintf(&b, "Category: %s (confidence %.2f)\n", category, confidence)
+
+	// Later rules that match make other categories candidates too
+	if len(candidates) > 1 {
+		names := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			names[i] = fmt.Sprintf("%s %.2f", candidate.Category, candidate.Score)
+		}
+		fmt.Fprintf(&b, "Candidates: %s\n", strings.Join(names, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// rulesReloadDelay lets an editor finish writing the rules file before it is
+// read again
+const rulesReloadDelay = 250 * time.Millisecond
+
+// WatchRules uses the rules file at path and reloads it whenever it changes
+// until ctx is done. Without the file the built-in rules are used. An invalid
+// file is an error here; once watching, errors are logged and the previous
+// rules stay in use.
+func (c *RuleBasedCategorizer) WatchRules(ctx context.Context, path string) error {
+	path = filepath.Clean(path)
+	if err := c.loadRulesFile(path); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Not watching rules file: %v", err)
+		return nil
+	}
+	// Watch the directory, as editors save by replacing the file, which ends
+	// a watch on the file itself
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		log.Printf("Not watching rules file: %v", err)
+		return nil
+	}
+
+	go c.watchRules(ctx, watcher, path)
+	return nil
+}
+
+// watchRules reloads the rules once the file has stopped changing
+func (c *RuleBasedCategorizer) watchRules(ctx context.Context, watcher *fsnotify.Watcher, path string) {
+	defer watcher.Close()
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == path {
+				reload = time.After(rulesReloadDelay)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Rules file watch error: %v", err)
+
+		case <-reload:
+			reload = nil
+			if err := c.loadRulesFile(path); err != nil {
+				log.Printf("Keeping previous categorization rules: %v", err)
+			}
+		}
+	}
+}
+
+// loadRulesFile switches to the rules in path, or to the built-in rules when
+// there is no such file
+func (c *RuleBasedCategorizer) loadRulesFile(path string) error {
+	rules, err := LoadRules(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.SetRules(createDefaultRules())
+		log.Printf("Using built-in categorization rules")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	c.SetRules(rules)
+	log.Printf("Loaded %d categorization rules from %s", len(rules), path)
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package recategorize categorizes stored activities again with the current
+// rules, keeping the categories it replaces in the category history.
+package recategorize
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/internal/processor"
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// batchSize is how many activities are read and updated at a time
+const batchSize = 500
+
+// historyReason marks category history entries written by recategorization
+const historyReason = "recategorize"
+
+// Storage reads and updates the stored activities
+type Storage interface {
+	CountActiveActivities(from, to time.Time) (int, error)
+	GetActiveActivitiesAfter(from, to time.Time, afterID int64, limit int) ([]*types.Activity, error)
+	UpdateCategories(changes []types.CategoryChange, reason string) (int, error)
+}
+
+// Categorizer assigns the new categories
+type Categorizer interface {
+	CategorizeCandidates(snapshot *types.WorkspaceSnapshot) (string, float64, []types.CategoryCandidate)
+}
+
+// Run categorizes the activities between from and to again in batches,
+// reporting the status after each batch. Idle, away, locked and paused time
+// keeps its category. With dryRun nothing is stored.
+func Run(ctx context.Context, storage Storage, categorizer Categorizer, from, to time.Time, dryRun bool,
+	progress func(types.RecategorizeStatus)) (types.RecategorizeStatus, error) {
+	started := time.Now()
+	status := types.RecategorizeStatus{Running: true, DryRun: dryRun, From: from, To: to, StartedAt: &started}
+
+	total, err := storage.CountActiveActivities(from, to)
+	if err != nil {
+		return finish(status, err), err
+	}
+	status.Total = total
+	progress(status)
+
+	var afterID int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return finish(status, err), err
+		}
+
+		activities, err := storage.GetActiveActivitiesAfter(from, to, afterID, batchSize)
+		if err != nil {
+			return finish(status, err), err
+		}
+		if len(activities) == 0 {
+			break
+		}
+
+		var changes []types.CategoryChange
+		for _, activity := range activities {
+			category, confidence, candidates := categorizer.CategorizeCandidates(processor.ActivitySnapshot(activity))
+			if category != activity.Category || confidence != activity.Confidence {
+				changes = append(changes, types.CategoryChange{
+					ActivityID:         activity.ID,
+					PreviousCategory:   activity.Category,
+					PreviousConfidence: activity.Confidence,
+					Category:           category,
+					Confidence:         confidence,
+					Candidates:         candidates,
+				})
+			}
+		}
+
+		changed := len(changes)
+		if !dryRun && len(changes) > 0 {
+			if changed, err = storage.UpdateCategories(changes, historyReason); err != nil {
+				return finish(status, err), err
+			}
+		}
+
+		afterID = activities[len(activities)-1].ID
+		status.Processed += len(activities)
+		status.Changed += changed
+		// Activities recorded during the run can add to the total
+		if status.Processed > status.Total {
+			status.Total = status.Processed
+		}
+		progress(status)
+	}
+
+	return finish(status, nil), nil
+}
+
+// finish marks a status as finished, with the error that stopped it
+func finish(status types.RecategorizeStatus, err error) types.RecategorizeStatus {
+	finished := time.Now()
+	status.Running = false
+	status.FinishedAt = &finished
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Runner runs one recategorization at a time in the background, as the
+// tracker does for the API
+type Runner struct {
+	ctx         context.Context
+	storage     Storage
+	categorizer Categorizer
+
+	mu      sync.Mutex
+	status  types.RecategorizeStatus
+	changes chan types.RecategorizeStatus
+}
+
+// NewRunner creates a runner whose jobs stop when ctx is done
+func NewRunner(ctx context.Context, storage Storage, categorizer Categorizer) *Runner {
+	return &Runner{
+		ctx:         ctx,
+		storage:     storage,
+		categorizer: categorizer,
+		changes:     make(chan types.RecategorizeStatus, 1),
+	}
+}
+
+// Start starts categorizing the activities between from and to again
+func (r *Runner) Start(from, to time.Time, dryRun bool) (types.RecategorizeStatus, error) {
+	if !from.Before(to) {
+		return types.RecategorizeStatus{}, fmt.Errorf("the start of the range must be before its end")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Running {
+		return r.status, fmt.Errorf("a recategorization is already running")
+	}
+
+	started := time.Now()
+	r.setStatus(types.RecategorizeStatus{Running: true, DryRun: dryRun, From: from, To: to, StartedAt: &started})
+	go func() {
+		status, _ := Run(r.ctx, r.storage, r.categorizer, from, to, dryRun, r.update)
+		r.update(status)
+	}()
+	return r.status, nil
+}
+
+// Status returns the status of the running or last recategorization
+func (r *Runner) Status() types.RecategorizeStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Changes delivers the status as a recategorization progresses. A receiver
+// that is behind gets the latest status.
+func (r *Runner) Changes() <-chan types.RecategorizeStatus {
+	return r.changes
+}
+
+// update records and publishes a new status
+func (r *Runner) update(status types.RecategorizeStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setStatus(status)
+}
+
+// setStatus records and publishes a status; r.mu must be held
+func (r *Runner) setStatus(status types.RecategorizeStatus) {
+	r.status = status
+	select {
+	case <-r.changes:
+	default:
+	}
+	r.changes <- status
+}
@@ -0,0 +1,221 @@
+// Package schedule decides when tracking runs from the working-hours
+// schedule in tracking.schedule.
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/faisalahmedsifat/compass/pkg/types"
+)
+
+// dateLayout is the format of holidays
+const dateLayout = "2006-01-02"
+
+// maxLookahead bounds the search for the next transition, long enough to
+// reach past a year of holidays
+const maxLookahead = 400
+
+// window is a daily tracking window in minutes since midnight, end exclusive
+type window struct {
+	start, end int
+}
+
+// Schedule is a weekly set of tracking windows with holidays off. A nil
+// Schedule does not limit tracking.
+type Schedule struct {
+	location *time.Location
+	weekly   [7][]window
+	holidays map[string]bool
+}
+
+// New compiles a schedule from the configuration, returning nil when the
+// schedule is disabled
+func New(config types.ScheduleConfig) (*Schedule, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	s := &Schedule{location: time.Local, holidays: make(map[string]bool)}
+	if config.Timezone != "" {
+		location, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", config.Timezone, err)
+		}
+		s.location = location
+	}
+
+	for day, windows := range config.Weekly {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday: %s", day)
+		}
+		for _, spec := range windows {
+			w, err := parseWindow(spec)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", day, err)
+			}
+			s.weekly[weekday] = append(s.weekly[weekday], w)
+		}
+		sorted := s.weekly[weekday]
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+		for i := 1; i < len(sorted); i++ {
+			if sorted[i].start < sorted[i-1].end {
+				return nil, fmt.Errorf("%s: windows overlap", day)
+			}
+		}
+	}
+
+	for _, holiday := range config.Holidays {
+		date, err := time.Parse(dateLayout, holiday)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", holiday)
+		}
+		s.holidays[date.Format(dateLayout)] = true
+	}
+
+	return s, nil
+}
+
+// parseWeekday parses a day name such as "monday" or "mon"
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// parseWindow parses a window such as "09:00-17:30"; "24:00" ends a window
+// at midnight
+func parseWindow(spec string) (window, error) {
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return window{}, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", spec)
+	}
+
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return window{}, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return window{}, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	if end <= start {
+		return window{}, fmt.Errorf("invalid window %q, windows past midnight are split into two days", spec)
+	}
+	return window{start: start, end: end}, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(clock string) (int, error) {
+	var hours, minutes int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hours, &minutes); err != nil || len(clock) != 5 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// InSchedule reports whether t falls in a tracking window
+func (s *Schedule) InSchedule(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+
+	t = t.In(s.location)
+	year, month, day := t.Date()
+	if s.holidays[t.Format(dateLayout)] {
+		return false
+	}
+	for _, w := range s.weekly[t.Weekday()] {
+		start := time.Date(year, month, day, 0, w.start, 0, 0, s.location)
+		end := time.Date(year, month, day, 0, w.end, 0, 0, s.location)
+		if !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns when InSchedule next changes after t, or the zero time when
+// it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	// Tracking only starts or stops at window edges, in order since windows
+	// do not overlap. Touching windows, including across midnight, and
+	// edges on holidays are not a transition.
+	in := s.InSchedule(t)
+	year, month, day := t.In(s.location).Date()
+	for i := 0; i < maxLookahead; i++ {
+		weekday := time.Date(year, month, day+i, 0, 0, 0, 0, s.location).Weekday()
+		for _, w := range s.weekly[weekday] {
+			for _, edge := range []int{w.start, w.end} {
+				at := time.Date(year, month, day+i, 0, edge, 0, 0, s.location)
+				if at.After(t) && s.InSchedule(at) != in {
+					return at
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Tracking reports whether tracking runs at t, either within the schedule
+// or before an override ends
+func (s *Schedule) Tracking(t, overrideUntil time.Time) bool {
+	return s.InSchedule(t) || t.Before(overrideUntil)
+}
+
+// NextChange returns when Tracking next changes after t, or the zero time
+// when it never does
+func (s *Schedule) NextChange(t, overrideUntil time.Time) time.Time {
+	tracking := s.Tracking(t, overrideUntil)
+	for at := t; ; {
+		next := s.Next(at)
+		if overrideUntil.After(at) && (next.IsZero() || overrideUntil.Before(next)) {
+			next = overrideUntil
+		}
+		if next.IsZero() || s.Tracking(next, overrideUntil) != tracking {
+			return next
+		}
+		at = next
+	}
+}
+
+// NextStart returns when the schedule next starts after t, the end an
+// override without an explicit duration runs to
+func (s *Schedule) NextStart(t time.Time) time.Time {
+	next := s.Next(t)
+	if s.InSchedule(t) && !next.IsZero() {
+		next = s.Next(next)
+	}
+	return next
+}
+
+// Status describes the schedule at now
+func (s *Schedule) Status(now, overrideUntil time.Time) types.ScheduleStatus {
+	status := types.ScheduleStatus{
+		Enabled:    s != nil,
+		InSchedule: s.InSchedule(now),
+		Tracking:   s.Tracking(now, overrideUntil),
+	}
+	if next := s.NextChange(now, overrideUntil); !next.IsZero() {
+		status.NextTransition = &next
+	}
+	if overrideUntil.After(now) {
+		status.OverrideUntil = &overrideUntil
+	}
+	return status
+}
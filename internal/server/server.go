@@ -19,12 +19,18 @@ import (
 type Server struct {
 	db       Database
 	addr     string
+	origins  map[string]bool // Web origins allowed to change state
 	upgrader websocket.Upgrader
 	clients  map[*websocket.Conn]bool
 	clientMu sync.RWMutex
 
 	activityChan chan *types.Activity
 	server       *http.Server
+
+	tabReceiver        TabReceiver
+	pauseController    PauseController
+	scheduleController ScheduleController
+	recategorizer      Recategorizer
 }
 
 // Database interface for the server
@@ -34,24 +40,81 @@ type Database interface {
 	GetStats(period string, date time.Time) (*types.Stats, error)
 	GetDatabaseStats() (map[string]interface{}, error)
 	GetScreenshot(activityID int64) ([]byte, error)
+	GetThumbnail(activityID int64) ([]byte, error)
+	GetResourceSamples(from, to time.Time, appName string, limit int) ([]*types.ResourceSample, error)
+	RelabelActivities(request types.RelabelRequest) (int, error)
+}
+
+// TabReceiver accepts active-tab events from the browser extension
+type TabReceiver interface {
+	UpdateTab(tab *types.BrowserTab)
+}
+
+// PauseController pauses and resumes tracking
+type PauseController interface {
+	Pause(d time.Duration) (types.PauseState, error)
+	Resume() (types.PauseState, error)
+	PauseState() types.PauseState
+	PauseChanges() <-chan types.PauseState
+}
+
+// ScheduleController reports and overrides the working-hours schedule
+type ScheduleController interface {
+	ScheduleStatus() types.ScheduleStatus
+	OverrideSchedule(d time.Duration) (types.ScheduleStatus, error)
+	ClearScheduleOverride() (types.ScheduleStatus, error)
+}
+
+// Recategorizer categorizes stored activities again in the background
+type Recategorizer interface {
+	Start(from, to time.Time, dryRun bool) (types.RecategorizeStatus, error)
+	Status() types.RecategorizeStatus
+	Changes() <-chan types.RecategorizeStatus
 }
 
 // NewServer creates a new web server
 func NewServer(config *types.ServerConfig, db Database, activityChan chan *types.Activity) *Server {
 	addr := fmt.Sprintf("%s:%s", config.Host, config.Port)
 
-	return &Server{
+	// Pages served by the API itself count as the dashboard too
+	origins := map[string]bool{"http://" + addr: true}
+	for _, origin := range config.DashboardOrigins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	s := &Server{
 		db:           db,
 		addr:         addr,
+		origins:      origins,
 		clients:      make(map[*websocket.Conn]bool),
 		activityChan: activityChan,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// Allow connections from localhost
-				return true
-			},
-		},
 	}
+	// WebSocket clients can pause and resume tracking
+	s.upgrader.CheckOrigin = s.trustedOrigin
+	return s
+}
+
+// SetTabReceiver sets where browser tab events posted to /api/browser/tab go
+func (s *Server) SetTabReceiver(receiver TabReceiver) {
+	s.tabReceiver = receiver
+}
+
+// SetPauseController sets what /api/pause, /api/resume and WebSocket pause
+// messages control. It must be called before Start.
+func (s *Server) SetPauseController(controller PauseController) {
+	s.pauseController = controller
+}
+
+// SetScheduleController sets the schedule reported by /api/health and
+// /api/schedule and overridden through /api/schedule/override
+func (s *Server) SetScheduleController(controller ScheduleController) {
+	s.scheduleController = controller
+}
+
+// SetRecategorizer sets what /api/recategorize starts and reports. It must
+// be called before Start.
+func (s *Server) SetRecategorizer(recategorizer Recategorizer) {
+	s.recategorizer = recategorizer
 }
 
 // Start starts the web server
@@ -59,12 +122,22 @@ func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 
 	// API endpoints with CORS wrapper
-	mux.HandleFunc("/api/activities", s.withCORS(s.handleActivities))
+	mux.HandleFunc("/api/activities", s.withDashboardCORS("GET, PATCH", s.handleActivities))
 	mux.HandleFunc("/api/stats", s.withCORS(s.handleStats))
 	mux.HandleFunc("/api/current", s.withCORS(s.handleCurrent))
 	mux.HandleFunc("/api/export", s.withCORS(s.handleExport))
 	mux.HandleFunc("/api/health", s.withCORS(s.handleHealth))
 	mux.HandleFunc("/api/screenshot/", s.withCORS(s.handleScreenshot))
+	mux.HandleFunc("/api/resources", s.withCORS(s.handleResources))
+	mux.HandleFunc("/api/pause", s.withDashboardCORS("GET, POST", s.handlePause))
+	mux.HandleFunc("/api/resume", s.withDashboardCORS("POST", s.handleResume))
+	mux.HandleFunc("/api/schedule", s.withCORS(s.handleSchedule))
+	mux.HandleFunc("/api/schedule/override", s.withDashboardCORS("POST, DELETE", s.handleScheduleOverride))
+	mux.HandleFunc("/api/recategorize", s.withDashboardCORS("GET, POST", s.handleRecategorize))
+
+	// Browser tab events come from the native messaging host, never from
+	// web pages, so this endpoint deliberately has no CORS headers
+	mux.HandleFunc("/api/browser/tab", s.handleBrowserTab)
 
 	// WebSocket for real-time updates
 	mux.HandleFunc("/ws", s.handleWebSocket)
@@ -85,9 +158,19 @@ func (s *Server) Start(ctx context.Context) error {
 	log.Printf("  GET  /api/health       - Server health check")
 	log.Printf("  GET  /api/current      - Current workspace state")
 	log.Printf("  GET  /api/activities   - Activity history")
+	log.Printf("  PATCH /api/activities  - Set the category of activities by hand")
 	log.Printf("  GET  /api/stats        - Workspace statistics")
 	log.Printf("  GET  /api/export       - Export data")
-	log.Printf("  GET  /api/screenshot/* - Activity screenshots")
+	log.Printf("  GET  /api/screenshot/* - Activity screenshots (?size=thumb|full)")
+	log.Printf("  GET  /api/resources    - Resource usage per application")
+	log.Printf("  GET  /api/pause        - Pause state")
+	log.Printf("  POST /api/pause        - Pause tracking (?for=30m)")
+	log.Printf("  POST /api/resume       - Resume tracking")
+	log.Printf("  GET  /api/schedule     - Working-hours schedule status")
+	log.Printf("  POST /api/schedule/override - Track outside the schedule (?for=2h)")
+	log.Printf("  GET  /api/recategorize - Recategorization progress")
+	log.Printf("  POST /api/recategorize - Categorize stored activities again (?from=&to=&dry_run=true)")
+	log.Printf("  POST /api/browser/tab  - Browser tab events")
 	log.Printf("  WS   /ws               - Real-time updates")
 
 	// Start server in goroutine
@@ -115,8 +198,12 @@ func (s *Server) Stop() error {
 	return nil
 }
 
-// handleActivities handles GET /api/activities
+// handleActivities handles GET and PATCH /api/activities
 func (s *Server) handleActivities(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPatch {
+		s.relabelActivities(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -164,6 +251,30 @@ func (s *Server) handleActivities(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// relabelActivities sets the category of the activities chosen by the JSON
+// body, {"ids": [...]} or {"from": ..., "to": ...}, by hand
+func (s *Server) relabelActivities(w http.ResponseWriter, r *http.Request) {
+	var request types.RelabelRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid relabel request: %v", err), http.StatusBadRequest)
+		return
+	}
+	request.Category = strings.TrimSpace(request.Category)
+	if err := request.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid relabel request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	relabeled, err := s.db.RelabelActivities(request)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to relabel activities: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.RelabelResult{Relabeled: relabeled})
+}
+
 // handleStats handles GET /api/stats
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -282,12 +393,16 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"timestamp": time.Now().Format(time.RFC3339),
 		"database":  stats,
 	}
+	if s.scheduleController != nil {
+		health["schedule"] = s.scheduleController.ScheduleStatus()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(health)
 }
 
-// handleScreenshot handles GET /api/screenshot/{id}
+// handleScreenshot handles GET /api/screenshot/{id}?size=thumb|full. Activities
+// captured before thumbnails existed are served full size.
 func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -303,7 +418,18 @@ func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get screenshot from database
-	screenshot, err := s.db.GetScreenshot(activityID)
+	var screenshot []byte
+	switch size := r.URL.Query().Get("size"); size {
+	case "", "full":
+		screenshot, err = s.db.GetScreenshot(activityID)
+	case "thumb":
+		if screenshot, err = s.db.GetThumbnail(activityID); err != nil {
+			screenshot, err = s.db.GetScreenshot(activityID)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Invalid size: %s", size), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "no screenshot found") {
 			http.Error(w, "Screenshot not found", http.StatusNotFound)
@@ -314,7 +440,8 @@ func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set appropriate headers
-	w.Header().Set("Content-Type", "image/png") // Assuming PNG format
+	// Screenshots are stored in the format configured when they were taken
+	w.Header().Set("Content-Type", http.DetectContentType(screenshot))
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(screenshot)))
 	w.Header().Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -325,6 +452,231 @@ func (s *Server) handleScreenshot(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleResources handles GET /api/resources
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	// Default to last 24 hours
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	limit := 1000
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		if parsed, err := time.Parse(time.RFC3339, fromStr); err == nil {
+			from = parsed
+		}
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		if parsed, err := time.Parse(time.RFC3339, toStr); err == nil {
+			to = parsed
+		}
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	samples, err := s.db.GetResourceSamples(from, to, query.Get("app"), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get resource samples: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(samples); err != nil {
+		log.Printf("Failed to encode resource samples: %v", err)
+	}
+}
+
+// handleBrowserTab handles POST /api/browser/tab
+func (s *Server) handleBrowserTab(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Requiring JSON makes browsers preflight cross-site requests, which
+	// fail without CORS headers
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if s.tabReceiver == nil {
+		http.Error(w, "Tab tracking is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var tab types.BrowserTab
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&tab); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid tab event: %v", err), http.StatusBadRequest)
+		return
+	}
+	if tab.URL == "" {
+		http.Error(w, "Tab URL is required", http.StatusBadRequest)
+		return
+	}
+
+	s.tabReceiver.UpdateTab(&tab)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePause handles GET and POST /api/pause
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if s.pauseController == nil {
+		http.Error(w, "Pausing is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var state types.PauseState
+	switch r.Method {
+	case http.MethodGet:
+		state = s.pauseController.PauseState()
+	case http.MethodPost:
+		var duration time.Duration
+		if value := r.URL.Query().Get("for"); value != "" {
+			var err error
+			if duration, err = time.ParseDuration(value); err != nil || duration <= 0 {
+				http.Error(w, "Invalid pause duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var err error
+		if state, err = s.pauseController.Pause(duration); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to pause: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleResume handles POST /api/resume
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.pauseController == nil {
+		http.Error(w, "Pausing is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	state, err := s.pauseController.Resume()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to resume: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleSchedule handles GET /api/schedule
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.scheduleController == nil {
+		http.Error(w, "Schedule is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduleController.ScheduleStatus())
+}
+
+// handleScheduleOverride handles POST and DELETE /api/schedule/override.
+// Without ?for= the override lasts until the schedule next starts.
+func (s *Server) handleScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	if s.scheduleController == nil {
+		http.Error(w, "Schedule is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var status types.ScheduleStatus
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		var duration time.Duration
+		if value := r.URL.Query().Get("for"); value != "" {
+			if duration, err = time.ParseDuration(value); err != nil || duration <= 0 {
+				http.Error(w, "Invalid override duration", http.StatusBadRequest)
+				return
+			}
+		}
+		status, err = s.scheduleController.OverrideSchedule(duration)
+	case http.MethodDelete:
+		status, err = s.scheduleController.ClearScheduleOverride()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to change schedule override: %v", err), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleRecategorize handles GET and POST /api/recategorize. POST starts
+// categorizing the activities between ?from= and ?to= (RFC3339, default now)
+// again; progress is broadcast to WebSocket clients as recategorize_status.
+func (s *Server) handleRecategorize(w http.ResponseWriter, r *http.Request) {
+	if s.recategorizer == nil {
+		http.Error(w, "Recategorization is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var status types.RecategorizeStatus
+	switch r.Method {
+	case http.MethodGet:
+		status = s.recategorizer.Status()
+	case http.MethodPost:
+		query := r.URL.Query()
+		from, err := time.Parse(time.RFC3339, query.Get("from"))
+		if err != nil {
+			http.Error(w, "Invalid or missing from time", http.StatusBadRequest)
+			return
+		}
+		to := time.Now()
+		if value := query.Get("to"); value != "" {
+			if to, err = time.Parse(time.RFC3339, value); err != nil {
+				http.Error(w, "Invalid to time", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if status, err = s.recategorizer.Start(from, to, query.Get("dry_run") == "true"); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to start recategorization: %v", err), http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
 // handleWebSocket handles WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := s.upgrader.Upgrade(w, r, nil)
@@ -353,21 +705,78 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			"data": current,
 		})
 	}
+	if s.pauseController != nil {
+		s.sendToClient(conn, map[string]interface{}{
+			"type": "pause_state",
+			"data": s.pauseController.PauseState(),
+		})
+	}
+	if s.recategorizer != nil {
+		if status := s.recategorizer.Status(); status.Running {
+			s.sendToClient(conn, map[string]interface{}{
+				"type": "recategorize_status",
+				"data": status,
+			})
+		}
+	}
 
 	// Keep connection alive and handle client messages
 	for {
-		_, _, err := conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
 			if !websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+		s.handleClientMessage(data)
+	}
+}
+
+// handleClientMessage handles a message from a WebSocket client:
+// {"type": "pause", "for": "30m"} or {"type": "resume"}. The new pause state
+// reaches every client through the broadcaster.
+func (s *Server) handleClientMessage(data []byte) {
+	var message struct {
+		Type string `json:"type"`
+		For  string `json:"for"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil || s.pauseController == nil {
+		return
+	}
+
+	var err error
+	switch message.Type {
+	case "pause":
+		var duration time.Duration
+		if message.For != "" {
+			if duration, err = time.ParseDuration(message.For); err != nil {
+				log.Printf("Invalid pause duration from WebSocket client: %v", err)
+				return
+			}
+		}
+		_, err = s.pauseController.Pause(duration)
+	case "resume":
+		_, err = s.pauseController.Resume()
+	default:
+		return
+	}
+	if err != nil {
+		log.Printf("WebSocket %s failed: %v", message.Type, err)
 	}
 }
 
 // startBroadcaster starts the WebSocket broadcaster
 func (s *Server) startBroadcaster(ctx context.Context) {
+	var pauseChanges <-chan types.PauseState
+	if s.pauseController != nil {
+		pauseChanges = s.pauseController.PauseChanges()
+	}
+	var recategorizeChanges <-chan types.RecategorizeStatus
+	if s.recategorizer != nil {
+		recategorizeChanges = s.recategorizer.Changes()
+	}
+
 	for {
 		select {
 		case activity := <-s.activityChan:
@@ -375,6 +784,16 @@ func (s *Server) startBroadcaster(ctx context.Context) {
 				"type": "activity_update",
 				"data": activity,
 			})
+		case state := <-pauseChanges:
+			s.broadcast(map[string]interface{}{
+				"type": "pause_state",
+				"data": state,
+			})
+		case status := <-recategorizeChanges:
+			s.broadcast(map[string]interface{}{
+				"type": "recategorize_status",
+				"data": status,
+			})
 		case <-ctx.Done():
 			return
 		}
@@ -405,17 +824,24 @@ func (s *Server) sendToClient(conn *websocket.Conn, message interface{}) {
 // exportCSV exports activities to CSV format
 func (s *Server) exportCSV(w http.ResponseWriter, activities []*types.Activity) {
 	// Write CSV header
-	fmt.Fprintln(w, "timestamp,app_name,window_title,category,focus_duration,total_windows")
+	fmt.Fprintln(w, "timestamp,app_name,window_title,category,focus_duration,total_windows,repository,branch")
 
 	// Write activities
 	for _, activity := range activities {
-		fmt.Fprintf(w, "%s,%s,%s,%s,%d,%d\n",
+		var repo types.Repository
+		if activity.Repository != nil {
+			repo = *activity.Repository
+		}
+
+		fmt.Fprintf(w, "%s,%s,%s,%s,%d,%d,%s,%s\n",
 			activity.Timestamp.Format(time.RFC3339),
 			csvEscape(activity.AppName),
 			csvEscape(activity.WindowTitle),
 			csvEscape(activity.Category),
 			activity.FocusDuration,
 			activity.TotalWindows,
+			csvEscape(repo.Root),
+			csvEscape(repo.Branch),
 		)
 	}
 }
@@ -447,6 +873,47 @@ func (s *Server) withCORS(handler http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// withDashboardCORS wraps handlers of endpoints that change state with the
+// given methods. Reading stays open to every origin as elsewhere, but other
+// methods are only accepted from the dashboard, so a web page cannot e.g.
+// resume tracking the user paused.
+func (s *Server) withDashboardCORS(methods string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trusted := s.trustedOrigin(r)
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && trusted {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", methods+", OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		}
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+			return
+		case http.MethodGet, http.MethodHead:
+		default:
+			if !trusted {
+				http.Error(w, "Origin not allowed to change state", http.StatusForbidden)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// trustedOrigin reports whether a request comes from the dashboard or from
+// outside a browser. Browsers send the origin of the page making requests
+// that change state and of WebSocket connections, so a request without one
+// comes from a program such as the CLI.
+func (s *Server) trustedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || s.origins[origin]
+}
+
 // handleCORS handles CORS preflight requests and serves API info
 func (s *Server) handleCORS(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
@@ -470,17 +937,24 @@ func (s *Server) handleCORS(w http.ResponseWriter, r *http.Request) {
 		"version":     "1.0.0",
 		"description": "Workspace tracking and analytics API",
 		"endpoints": map[string]string{
-			"/api/health":       "Server health check",
-			"/api/current":      "Current workspace state",
-			"/api/activities":   "Activity history with optional filters",
-			"/api/stats":        "Workspace statistics",
-			"/api/export":       "Export data in JSON/CSV format",
-			"/api/screenshot/*": "Activity screenshots",
-			"/ws":               "WebSocket for real-time updates",
+			"/api/health":            "Server health check",
+			"/api/current":           "Current workspace state",
+			"/api/activities":        "Activity history with optional filters (GET) or set the category of activities by hand (PATCH)",
+			"/api/stats":             "Workspace statistics",
+			"/api/export":            "Export data in JSON/CSV format",
+			"/api/screenshot/*":      "Activity screenshots (?size=thumb|full)",
+			"/api/resources":         "CPU, memory and I/O samples per application",
+			"/api/pause":             "Pause state (GET) or pause tracking (POST, ?for=30m)",
+			"/api/resume":            "Resume tracking (POST)",
+			"/api/schedule":          "Whether tracking is in the working-hours schedule and when that changes",
+			"/api/schedule/override": "Track outside the schedule (POST, ?for=2h) or end the override (DELETE)",
+			"/api/recategorize":      "Recategorization progress (GET) or categorize stored activities again (POST, ?from=&to=&dry_run=true)",
+			"/api/browser/tab":       "Active browser tab events (POST, native host only)",
+			"/ws":                    "WebSocket for real-time updates",
 		},
 		"websocket": map[string]string{
 			"url":      "ws://" + r.Host + "/ws",
-			"messages": "Receives current_workspace and activity_update events",
+			"messages": "Receives current_workspace, activity_update, pause_state and recategorize_status events; accepts pause and resume",
 		},
 	}
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/faisalahmedsifat/compass/pkg/types"
@@ -14,6 +15,8 @@ import (
 // Database handles all database operations
 type Database struct {
 	db *sql.DB
+
+	splitCategoryTime bool
 }
 
 // NewDatabase creates a new database connection
@@ -34,12 +37,20 @@ func NewDatabase(dbPath string) (*Database, error) {
 	return database, nil
 }
 
+// SetSplitCategoryTime makes stats apportion the time of each activity
+// across its category candidates by score
+func (d *Database) SetSplitCategoryTime(split bool) {
+	d.splitCategoryTime = split
+}
+
 // Close closes the database connection
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-// SaveActivity saves an activity record to the database
+// SaveActivity saves an activity record to the database. Its screenshot,
+// the activity and its metadata are stored in one transaction, so a failure
+// leaves none of them behind.
 func (d *Database) SaveActivity(activity *types.Activity) error {
 	// Serialize windows to JSON
 	windowsJSON, err := json.Marshal(activity.AllWindows)
@@ -47,15 +58,64 @@ func (d *Database) SaveActivity(activity *types.Activity) error {
 		return fmt.Errorf("failed to marshal windows: %w", err)
 	}
 
+	var monitorsJSON []byte
+	if len(activity.Monitors) > 0 {
+		if monitorsJSON, err = json.Marshal(activity.Monitors); err != nil {
+			return fmt.Errorf("failed to marshal monitors: %w", err)
+		}
+	}
+
+	var connectionsJSON []byte
+	if len(activity.Connections) > 0 {
+		if connectionsJSON, err = json.Marshal(activity.Connections); err != nil {
+			return fmt.Errorf("failed to marshal connections: %w", err)
+		}
+	}
+
+	candidatesJSON, err := marshalCandidates(activity.Candidates)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin saving activity: %w", err)
+	}
+	defer tx.Rollback()
+
+	screenshotID := activity.ScreenshotID
+	if activity.Screenshot != nil {
+		if screenshotID, err = saveScreenshot(tx, activity); err != nil {
+			return err
+		}
+	}
+
+	// A referenced screenshot that has since been cleaned up is dropped
+	// rather than failing the foreign key
 	query := `
 		INSERT INTO activities (
-			timestamp, app_name, window_title, process_id, is_active,
-			focus_duration, total_windows, window_list, category, confidence, screenshot
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			timestamp, start_time, end_time, app_name, window_title, process_id, is_active,
+			focus_duration, total_windows, window_list, monitor, monitor_layout,
+			repo_root, repo_remote, repo_branch, tab_url, tab_title, tab_domain,
+			connections, category, confidence, candidates, screenshot_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			(SELECT id FROM screenshots WHERE id = ?))
 	`
 
-	result, err := d.db.Exec(query,
+	var repo types.Repository
+	if activity.Repository != nil {
+		repo = *activity.Repository
+	}
+
+	var tab types.BrowserTab
+	if activity.Tab != nil {
+		tab = *activity.Tab
+	}
+
+	result, err := tx.Exec(query,
 		activity.Timestamp,
+		activity.StartTime,
+		activity.EndTime,
 		activity.AppName,
 		activity.WindowTitle,
 		activity.ProcessID,
@@ -63,9 +123,19 @@ func (d *Database) SaveActivity(activity *types.Activity) error {
 		activity.FocusDuration,
 		activity.TotalWindows,
 		string(windowsJSON),
+		nullString(activity.Monitor),
+		nullString(string(monitorsJSON)),
+		nullString(repo.Root),
+		nullString(repo.Remote),
+		nullString(repo.Branch),
+		nullString(tab.URL),
+		nullString(tab.Title),
+		nullString(tab.Domain()),
+		nullString(string(connectionsJSON)),
 		activity.Category,
 		activity.Confidence,
-		activity.Screenshot,
+		candidatesJSON,
+		screenshotID,
 	)
 
 	if err != nil {
@@ -77,38 +147,420 @@ func (d *Database) SaveActivity(activity *types.Activity) error {
 		return fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
+	if err := saveMetadata(tx, id, activity.Metadata); err != nil {
+		return err
+	}
+
+	if err := saveResourceSamples(tx, activity.ResourceSamples); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit activity: %w", err)
+	}
 	activity.ID = id
+	activity.ScreenshotID = screenshotID
+	return nil
+}
+
+// saveScreenshot stores the screenshot of an activity, returning its ID
+func saveScreenshot(tx *sql.Tx, activity *types.Activity) (int64, error) {
+	result, err := tx.Exec(
+		`INSERT INTO screenshots (timestamp, hash, image, thumbnail) VALUES (?, ?, ?, ?)`,
+		activity.Timestamp,
+		// SQLite integers are signed; the hash is stored by its bits
+		int64(activity.ScreenshotHash),
+		activity.Screenshot,
+		activity.Thumbnail,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// saveResourceSamples stores the resource samples taken with an activity
+func saveResourceSamples(tx *sql.Tx, samples []types.ResourceSample) error {
+	query := `
+		INSERT INTO resource_samples (
+			timestamp, duration_ms, app_name, process_id, process_count,
+			cpu_percent, rss_bytes, read_bytes, write_bytes
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	for _, sample := range samples {
+		if _, err := tx.Exec(query,
+			sample.Timestamp,
+			sample.Duration.Milliseconds(),
+			sample.AppName,
+			sample.ProcessID,
+			sample.Processes,
+			sample.CPUPercent,
+			sample.RSSBytes,
+			sample.ReadBytes,
+			sample.WriteBytes,
+		); err != nil {
+			return fmt.Errorf("failed to save resource sample: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveMetadata stores the enricher metadata of an activity, one row per key
+func saveMetadata(tx *sql.Tx, activityID int64, metadata types.Metadata) error {
+	for key, value := range metadata {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata %s: %w", key, err)
+		}
+
+		if _, err := tx.Exec(
+			`INSERT OR REPLACE INTO activity_metadata (activity_id, key, value) VALUES (?, ?, ?)`,
+			activityID, key, string(valueJSON),
+		); err != nil {
+			return fmt.Errorf("failed to save metadata %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// metadataBatchSize keeps metadata lookups below SQLite's bound parameter limit
+const metadataBatchSize = 500
+
+// loadMetadata attaches the stored metadata to activities
+func (d *Database) loadMetadata(activities []*types.Activity) error {
+	for start := 0; start < len(activities); start += metadataBatchSize {
+		end := start + metadataBatchSize
+		if end > len(activities) {
+			end = len(activities)
+		}
+		if err := d.loadMetadataBatch(activities[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMetadataBatch attaches the stored metadata to one batch of activities
+func (d *Database) loadMetadataBatch(activities []*types.Activity) error {
+	byID := make(map[int64]*types.Activity, len(activities))
+	placeholders := make([]string, 0, len(activities))
+	args := make([]interface{}, 0, len(activities))
+	for _, activity := range activities {
+		byID[activity.ID] = activity
+		placeholders = append(placeholders, "?")
+		args = append(args, activity.ID)
+	}
+
+	rows, err := d.db.Query(
+		`SELECT activity_id, key, value FROM activity_metadata WHERE activity_id IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query metadata: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var activityID int64
+		var key, valueJSON string
+		if err := rows.Scan(&activityID, &key, &valueJSON); err != nil {
+			return fmt.Errorf("failed to scan metadata: %w", err)
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+			log.Printf("Failed to unmarshal metadata %s of activity %d: %v", key, activityID, err)
+			continue
+		}
+
+		activity := byID[activityID]
+		if activity.Metadata == nil {
+			activity.Metadata = make(types.Metadata)
+		}
+		activity.Metadata[key] = value
+	}
+	return rows.Err()
+}
+
+// MarkIdle reclassifies activities that ended within (from, to] as idle time.
+// It is used when idle detection notices that the user left before the
+// threshold expired.
+func (d *Database) MarkIdle(from, to time.Time) error {
+	query := `
+		UPDATE activities SET category = ?, is_active = 0
+		WHERE timestamp > ? AND timestamp <= ? AND is_active = 1
+	`
+
+	if _, err := d.db.Exec(query, types.CategoryIdle, from, to); err != nil {
+		return fmt.Errorf("failed to mark idle activities: %w", err)
+	}
+	return nil
+}
+
+// GetPauseState returns the stored pause state; tracking is not paused when
+// none was stored
+func (d *Database) GetPauseState() (types.PauseState, error) {
+	var state types.PauseState
+
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE key = 'pause'").Scan(&value)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read pause state: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return state, fmt.Errorf("invalid pause state: %w", err)
+	}
+	return state, nil
+}
+
+// SavePauseState stores the pause state so it survives restarts
+func (d *Database) SavePauseState(state types.PauseState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pause state: %w", err)
+	}
+
+	query := `
+		INSERT INTO settings (key, value, updated_at) VALUES ('pause', ?, datetime('now'))
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+	if _, err := d.db.Exec(query, string(value)); err != nil {
+		return fmt.Errorf("failed to save pause state: %w", err)
+	}
+	return nil
+}
+
+// GetScheduleOverride returns until when tracking runs outside the
+// schedule, the zero time when it is not overridden
+func (d *Database) GetScheduleOverride() (time.Time, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE key = 'schedule_override'").Scan(&value)
+	if err == sql.ErrNoRows || (err == nil && value == "") {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read schedule override: %w", err)
+	}
+
+	until, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule override: %w", err)
+	}
+	return until, nil
+}
+
+// SaveScheduleOverride stores until when tracking runs outside the
+// schedule; the zero time clears the override
+func (d *Database) SaveScheduleOverride(until time.Time) error {
+	value := ""
+	if !until.IsZero() {
+		value = until.UTC().Format(time.RFC3339Nano)
+	}
+
+	query := `
+		INSERT INTO settings (key, value, updated_at) VALUES ('schedule_override', ?, datetime('now'))
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+	if _, err := d.db.Exec(query, value); err != nil {
+		return fmt.Errorf("failed to save schedule override: %w", err)
+	}
 	return nil
 }
 
 // GetActivities retrieves activities within a time range
 func (d *Database) GetActivities(from, to time.Time, limit int) ([]*types.Activity, error) {
+	return d.queryActivities("WHERE timestamp BETWEEN ? AND ? ORDER BY timestamp DESC LIMIT ?", from, to, limit)
+}
+
+// GetActivity retrieves an activity by ID
+func (d *Database) GetActivity(id int64) (*types.Activity, error) {
+	activities, err := d.queryActivities("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("activity %d not found", id)
+	}
+	return activities[0], nil
+}
+
+// CountActiveActivities counts the activities within a time range that were
+// not idle, away, locked or paused and were not labelled by hand
+func (d *Database) CountActiveActivities(from, to time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM activities WHERE timestamp BETWEEN ? AND ? AND is_active = 1 AND manual = 0`
+	if err := d.db.QueryRow(query, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count activities: %w", err)
+	}
+	return count, nil
+}
+
+// GetActiveActivitiesAfter retrieves the next batch of activities counted by
+// CountActiveActivities, in ID order after afterID
+func (d *Database) GetActiveActivitiesAfter(from, to time.Time, afterID int64, limit int) ([]*types.Activity, error) {
+	return d.queryActivities("WHERE timestamp BETWEEN ? AND ? AND is_active = 1 AND manual = 0 AND id > ? ORDER BY id LIMIT ?",
+		from, to, afterID, limit)
+}
+
+// UpdateCategories stores new categories, keeping the replaced ones in the
+// category history with the reason. Activities that changed since they were
+// read, e.g. by being marked idle or labelled by hand, are left alone. It
+// returns how many activities were updated.
+func (d *Database) UpdateCategories(changes []types.CategoryChange, reason string) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin category update: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, change := range changes {
+		candidatesJSON, err := marshalCandidates(change.Candidates)
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := tx.Exec(`
+			UPDATE activities SET category = ?, confidence = ?, candidates = ?
+			WHERE id = ? AND is_active = 1 AND manual = 0 AND category = ? AND confidence = ?
+		`, change.Category, change.Confidence, candidatesJSON,
+			change.ActivityID, change.PreviousCategory, change.PreviousConfidence)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update category: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO category_history (activity_id, category, confidence, replaced_at, reason)
+			VALUES (?, ?, ?, ?, ?)
+		`, change.ActivityID, change.PreviousCategory, change.PreviousConfidence, time.Now(), reason)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record category history: %w", err)
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit category update: %w", err)
+	}
+	return updated, nil
+}
+
+// RelabelActivities sets the category of the activities a validated request
+// chooses and marks it as set by hand, so recategorization keeps it. Each
+// change is recorded in the corrections table. Idle, away, locked and paused
+// time keeps its category. It returns how many activities changed.
+func (d *Database) RelabelActivities(request types.RelabelRequest) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin relabel: %w", err)
+	}
+	defer tx.Rollback()
+
+	where := "timestamp BETWEEN ? AND ?"
+	args := []interface{}{}
+	if request.From != nil {
+		args = append(args, *request.From, *request.To)
+	} else {
+		where = "id IN (?" + strings.Repeat(", ?", len(request.IDs)-1) + ")"
+		for _, id := range request.IDs {
+			args = append(args, id)
+		}
+	}
+	args = append(args, request.Category)
+
+	rows, err := tx.Query(`
+		SELECT id, category, confidence FROM activities
+		WHERE `+where+` AND is_active = 1 AND NOT (manual = 1 AND category = ?)
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query activities to relabel: %w", err)
+	}
+	var changes []types.CategoryChange
+	for rows.Next() {
+		var change types.CategoryChange
+		if err := rows.Scan(&change.ActivityID, &change.PreviousCategory, &change.PreviousConfidence); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan activity to relabel: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// The user is certain of the category
+	candidatesJSON, err := marshalCandidates([]types.CategoryCandidate{{Category: request.Category, Score: 1.0}})
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, change := range changes {
+		_, err := tx.Exec(`
+			UPDATE activities SET category = ?, confidence = 1.0, candidates = ?, manual = 1
+			WHERE id = ?
+		`, request.Category, candidatesJSON, change.ActivityID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to relabel activity: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO corrections (activity_id, previous_category, previous_confidence, category, corrected_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, change.ActivityID, change.PreviousCategory, change.PreviousConfidence, request.Category, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record correction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit relabel: %w", err)
+	}
+	return len(changes), nil
+}
+
+// queryActivities reads the activities selected by a WHERE clause with their
+// metadata
+func (d *Database) queryActivities(where string, args ...interface{}) ([]*types.Activity, error) {
 	query := `
-		SELECT id, timestamp, app_name, window_title, process_id, is_active,
-		       focus_duration, total_windows, window_list, category, confidence,
-		       CASE WHEN screenshot IS NOT NULL THEN 1 ELSE 0 END as has_screenshot
+		SELECT id, timestamp, start_time, end_time, app_name, window_title, process_id, is_active,
+		       focus_duration, total_windows, window_list, monitor, monitor_layout,
+		       repo_root, repo_remote, repo_branch, tab_url, tab_title,
+		       connections, category, confidence, candidates, manual, screenshot_id
 		FROM activities
-		WHERE timestamp BETWEEN ? AND ?
-		ORDER BY timestamp DESC
-		LIMIT ?
-	`
+	` + where
 
-	rows, err := d.db.Query(query, from, to, limit)
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query activities: %w", err)
 	}
 	defer rows.Close()
 
-	activities := make([]*types.Activity, 0, limit)
+	activities := []*types.Activity{}
 
 	for rows.Next() {
 		activity := &types.Activity{}
 		var windowsJSON string
-		var hasScreenshot int
+		var screenshotID sql.NullInt64
+		var startTime, endTime sql.NullTime
+		var monitor, monitorsJSON sql.NullString
+		var repoRoot, repoRemote, repoBranch sql.NullString
+		var tabURL, tabTitle sql.NullString
+		var connectionsJSON, candidatesJSON sql.NullString
 
 		err := rows.Scan(
 			&activity.ID,
 			&activity.Timestamp,
+			&startTime,
+			&endTime,
 			&activity.AppName,
 			&activity.WindowTitle,
 			&activity.ProcessID,
@@ -116,16 +568,38 @@ func (d *Database) GetActivities(from, to time.Time, limit int) ([]*types.Activi
 			&activity.FocusDuration,
 			&activity.TotalWindows,
 			&windowsJSON,
+			&monitor,
+			&monitorsJSON,
+			&repoRoot,
+			&repoRemote,
+			&repoBranch,
+			&tabURL,
+			&tabTitle,
+			&connectionsJSON,
 			&activity.Category,
 			&activity.Confidence,
-			&hasScreenshot,
+			&candidatesJSON,
+			&activity.Manual,
+			&screenshotID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan activity: %w", err)
 		}
 
 		// Set screenshot flag
-		activity.HasScreenshot = hasScreenshot == 1
+		activity.ScreenshotID = screenshotID.Int64
+		activity.HasScreenshot = screenshotID.Valid
+
+		// Activities recorded before segment boundaries were stored end at
+		// their timestamp and span their focus duration
+		activity.EndTime = activity.Timestamp
+		if endTime.Valid {
+			activity.EndTime = endTime.Time
+		}
+		activity.StartTime = activity.EndTime.Add(-time.Duration(activity.FocusDuration) * time.Second)
+		if startTime.Valid {
+			activity.StartTime = startTime.Time
+		}
 
 		// Deserialize windows
 		if err := json.Unmarshal([]byte(windowsJSON), &activity.AllWindows); err != nil {
@@ -133,26 +607,45 @@ func (d *Database) GetActivities(from, to time.Time, limit int) ([]*types.Activi
 			activity.AllWindows = []types.Window{} // Empty fallback
 		}
 
+		activity.Monitor = monitor.String
+		activity.Monitors = parseMonitors(monitorsJSON)
+		if repoRoot.Valid {
+			activity.Repository = &types.Repository{
+				Root:   repoRoot.String,
+				Remote: repoRemote.String,
+				Branch: repoBranch.String,
+			}
+		}
+		if tabURL.Valid {
+			activity.Tab = &types.BrowserTab{URL: tabURL.String, Title: tabTitle.String}
+		}
+		activity.Connections = parseConnections(connectionsJSON)
+		activity.Candidates = parseCandidates(candidatesJSON)
+
 		activities = append(activities, activity)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return activities, rows.Err()
+	return activities, d.loadMetadata(activities)
 }
 
 // GetCurrentWorkspace gets the most recent workspace state
 func (d *Database) GetCurrentWorkspace() (*types.CurrentWorkspace, error) {
 	query := `
-		SELECT app_name, window_title, window_list, category, timestamp, focus_duration
+		SELECT app_name, window_title, window_list, monitor_layout, category, candidates, timestamp, focus_duration
 		FROM activities
 		ORDER BY timestamp DESC
 		LIMIT 1
 	`
 
 	var appName, windowTitle, windowsJSON, category string
+	var monitorsJSON, candidatesJSON sql.NullString
 	var timestamp time.Time
 	var focusDuration int
 
-	err := d.db.QueryRow(query).Scan(&appName, &windowTitle, &windowsJSON, &category, &timestamp, &focusDuration)
+	err := d.db.QueryRow(query).Scan(&appName, &windowTitle, &windowsJSON, &monitorsJSON, &category, &candidatesJSON, &timestamp, &focusDuration)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Return empty workspace with helpful message
@@ -195,7 +688,9 @@ func (d *Database) GetCurrentWorkspace() (*types.CurrentWorkspace, error) {
 		ActiveWindow:    activeWindow,
 		AllWindows:      windows,
 		WindowCount:     len(windows),
+		Monitors:        parseMonitors(monitorsJSON),
 		Category:        category,
+		Candidates:      parseCandidates(candidatesJSON),
 		FocusTime:       formatDuration(time.Duration(focusDuration) * time.Second),
 		ContextSwitches: contextSwitches,
 		Timestamp:       timestamp,
@@ -229,6 +724,9 @@ func (d *Database) GetStats(period string, date time.Time) (*types.Stats, error)
 		Period:     period,
 		ByApp:      make(map[string]time.Duration),
 		ByCategory: make(map[string]time.Duration),
+		ByMonitor:  make(map[string]*types.MonitorUsage),
+		ByRepo:     make(map[string]*types.RepoUsage),
+		ByDomain:   make(map[string]time.Duration),
 	}
 
 	// Get app statistics
@@ -258,29 +756,37 @@ func (d *Database) GetStats(period string, date time.Time) (*types.Stats, error)
 		totalTime += duration
 	}
 
-	// Get category statistics
-	categoryQuery := `
-		SELECT category, SUM(focus_duration) as total_seconds
-		FROM activities
-		WHERE timestamp BETWEEN ? AND ? AND is_active = 1
-		GROUP BY category
-		ORDER BY total_seconds DESC
-	`
-
-	rows, err = d.db.Query(categoryQuery, from, to)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query category stats: %w", err)
-	}
-	defer rows.Close()
+	// Get category statistics; idle, suspended, locked and paused time is
+	// reported in its own category but is not part of the total active time
+	if d.splitCategoryTime {
+		if stats.ByCategory, err = d.getSplitCategoryUsage(from, to); err != nil {
+			return nil, err
+		}
+	} else {
+		categoryQuery := `
+			SELECT category, SUM(focus_duration) as total_seconds
+			FROM activities
+			WHERE timestamp BETWEEN ? AND ? AND (is_active = 1 OR category IN (?, ?, ?, ?))
+			GROUP BY category
+			ORDER BY total_seconds DESC
+		`
 
-	for rows.Next() {
-		var category string
-		var seconds int
-		if err := rows.Scan(&category, &seconds); err != nil {
-			continue
+		rows, err = d.db.Query(categoryQuery, from, to,
+			types.CategoryIdle, types.CategoryAway, types.CategoryLocked, types.CategoryPaused)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query category stats: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var category string
+			var seconds int
+			if err := rows.Scan(&category, &seconds); err != nil {
+				continue
+			}
+			duration := time.Duration(seconds) * time.Second
+			stats.ByCategory[category] = duration
 		}
-		duration := time.Duration(seconds) * time.Second
-		stats.ByCategory[category] = duration
 	}
 
 	stats.TotalTime = totalTime
@@ -294,12 +800,31 @@ func (d *Database) GetStats(period string, date time.Time) (*types.Stats, error)
 	// Get patterns
 	stats.Patterns, _ = d.getPatterns(from, to)
 
+	// Get time by monitor
+	if byMonitor, err := d.getMonitorUsage(from, to); err == nil {
+		stats.ByMonitor = byMonitor
+	}
+
+	// Get time by repository and branch
+	if byRepo, err := d.getRepoUsage(from, to); err == nil {
+		stats.ByRepo = byRepo
+	}
+
+	// Get time by website
+	if byDomain, err := d.getDomainUsage(from, to); err == nil {
+		stats.ByDomain = byDomain
+	}
+
 	return stats, nil
 }
 
 // GetScreenshot retrieves a screenshot by activity ID
 func (d *Database) GetScreenshot(activityID int64) ([]byte, error) {
-	query := `SELECT screenshot FROM activities WHERE id = ? AND screenshot IS NOT NULL`
+	query := `
+		SELECT s.image FROM activities a
+		JOIN screenshots s ON s.id = a.screenshot_id
+		WHERE a.id = ?
+	`
 
 	var screenshot []byte
 	err := d.db.QueryRow(query, activityID).Scan(&screenshot)
@@ -313,6 +838,76 @@ func (d *Database) GetScreenshot(activityID int64) ([]byte, error) {
 	return screenshot, nil
 }
 
+// GetThumbnail retrieves the screenshot thumbnail of an activity
+func (d *Database) GetThumbnail(activityID int64) ([]byte, error) {
+	query := `
+		SELECT s.thumbnail FROM activities a
+		JOIN screenshots s ON s.id = a.screenshot_id
+		WHERE a.id = ? AND s.thumbnail IS NOT NULL
+	`
+
+	var thumbnail []byte
+	err := d.db.QueryRow(query, activityID).Scan(&thumbnail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no thumbnail found for activity %d", activityID)
+		}
+		return nil, fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+
+	return thumbnail, nil
+}
+
+// getSplitCategoryUsage sums time per category, sharing the time of each
+// activity among its category candidates in proportion to their scores.
+// Activities without candidates count fully for their category.
+func (d *Database) getSplitCategoryUsage(from, to time.Time) (map[string]time.Duration, error) {
+	query := `
+		SELECT category, candidates, focus_duration
+		FROM activities
+		WHERE timestamp BETWEEN ? AND ? AND (is_active = 1 OR category IN (?, ?, ?, ?))
+	`
+
+	rows, err := d.db.Query(query, from, to,
+		types.CategoryIdle, types.CategoryAway, types.CategoryLocked, types.CategoryPaused)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category stats: %w", err)
+	}
+	defer rows.Close()
+
+	seconds := make(map[string]float64)
+	for rows.Next() {
+		var category string
+		var candidatesJSON sql.NullString
+		var duration int
+		if err := rows.Scan(&category, &candidatesJSON, &duration); err != nil {
+			continue
+		}
+
+		candidates := parseCandidates(candidatesJSON)
+		total := 0.0
+		for _, candidate := range candidates {
+			total += candidate.Score
+		}
+		if total <= 0 {
+			seconds[category] += float64(duration)
+			continue
+		}
+		for _, candidate := range candidates {
+			seconds[candidate.Category] += float64(duration) * candidate.Score / total
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[string]time.Duration, len(seconds))
+	for category, s := range seconds {
+		byCategory[category] = time.Duration(s * float64(time.Second)).Round(time.Second)
+	}
+	return byCategory, nil
+}
+
 // getContextSwitches counts context switches in a time period
 func (d *Database) getContextSwitches(from, to time.Time) (int, error) {
 	query := `
@@ -387,6 +982,188 @@ func (d *Database) getPatterns(from, to time.Time) ([]types.Pattern, error) {
 	return patterns, nil
 }
 
+// getDomainUsage sums active time per website domain
+func (d *Database) getDomainUsage(from, to time.Time) (map[string]time.Duration, error) {
+	query := `
+		SELECT tab_domain, SUM(focus_duration)
+		FROM activities
+		WHERE timestamp BETWEEN ? AND ? AND is_active = 1 AND tab_domain IS NOT NULL
+		GROUP BY tab_domain
+	`
+
+	rows, err := d.db.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query domain usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]time.Duration)
+	for rows.Next() {
+		var domain string
+		var seconds int
+		if err := rows.Scan(&domain, &seconds); err != nil {
+			continue
+		}
+		usage[domain] = time.Duration(seconds) * time.Second
+	}
+
+	return usage, rows.Err()
+}
+
+// getRepoUsage sums active time per repository and branch
+func (d *Database) getRepoUsage(from, to time.Time) (map[string]*types.RepoUsage, error) {
+	query := `
+		SELECT repo_root, MAX(repo_remote), COALESCE(repo_branch, ''), SUM(focus_duration)
+		FROM activities
+		WHERE timestamp BETWEEN ? AND ? AND is_active = 1 AND repo_root IS NOT NULL
+		GROUP BY repo_root, repo_branch
+	`
+
+	rows, err := d.db.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repository usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]*types.RepoUsage)
+	for rows.Next() {
+		var root, branch string
+		var remote sql.NullString
+		var seconds int
+		if err := rows.Scan(&root, &remote, &branch, &seconds); err != nil {
+			continue
+		}
+
+		repo := usage[root]
+		if repo == nil {
+			repo = &types.RepoUsage{ByBranch: make(map[string]time.Duration)}
+			usage[root] = repo
+		}
+		if remote.String != "" {
+			repo.Remote = remote.String
+		}
+
+		duration := time.Duration(seconds) * time.Second
+		repo.TotalTime += duration
+		repo.ByBranch[branch] += duration
+	}
+
+	return usage, rows.Err()
+}
+
+// getMonitorUsage breaks active time down by monitor. Time counts as focused
+// time on the monitor holding the focused window and as reference time on
+// every other monitor showing windows.
+func (d *Database) getMonitorUsage(from, to time.Time) (map[string]*types.MonitorUsage, error) {
+	query := `
+		SELECT monitor, monitor_layout, window_list, focus_duration
+		FROM activities
+		WHERE timestamp BETWEEN ? AND ? AND is_active = 1
+		  AND monitor IS NOT NULL AND monitor != ''
+	`
+
+	rows, err := d.db.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query monitor usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]*types.MonitorUsage)
+	usageFor := func(name string) *types.MonitorUsage {
+		if usage[name] == nil {
+			usage[name] = &types.MonitorUsage{ReferenceApps: make(map[string]time.Duration)}
+		}
+		return usage[name]
+	}
+
+	for rows.Next() {
+		var monitor, windowsJSON string
+		var monitorsJSON sql.NullString
+		var seconds int
+		if err := rows.Scan(&monitor, &monitorsJSON, &windowsJSON, &seconds); err != nil {
+			continue
+		}
+		duration := time.Duration(seconds) * time.Second
+		usageFor(monitor).FocusedTime += duration
+
+		var windows []types.Window
+		if err := json.Unmarshal([]byte(windowsJSON), &windows); err != nil {
+			continue
+		}
+
+		names := make(map[int]string)
+		for _, m := range parseMonitors(monitorsJSON) {
+			names[m.Index] = m.Name
+		}
+
+		// Count each app once per monitor and activity
+		seen := make(map[string]bool)
+		for _, w := range windows {
+			name := names[w.Monitor]
+			if name == "" || name == monitor {
+				continue
+			}
+
+			reference := usageFor(name)
+			if !seen[name] {
+				seen[name] = true
+				reference.ReferenceTime += duration
+			}
+			if key := name + "\x00" + w.AppName; !seen[key] {
+				seen[key] = true
+				reference.ReferenceApps[w.AppName] += duration
+			}
+		}
+	}
+
+	return usage, rows.Err()
+}
+
+// GetResourceSamples retrieves resource samples within a time range,
+// optionally for one application
+func (d *Database) GetResourceSamples(from, to time.Time, appName string, limit int) ([]*types.ResourceSample, error) {
+	query := `
+		SELECT id, timestamp, duration_ms, app_name, process_id, process_count,
+		       cpu_percent, rss_bytes, read_bytes, write_bytes
+		FROM resource_samples
+		WHERE timestamp BETWEEN ? AND ? AND (? = '' OR app_name = ?)
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, from, to, appName, appName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query resource samples: %w", err)
+	}
+	defer rows.Close()
+
+	samples := make([]*types.ResourceSample, 0)
+	for rows.Next() {
+		sample := &types.ResourceSample{}
+		var durationMs int64
+
+		if err := rows.Scan(
+			&sample.ID,
+			&sample.Timestamp,
+			&durationMs,
+			&sample.AppName,
+			&sample.ProcessID,
+			&sample.Processes,
+			&sample.CPUPercent,
+			&sample.RSSBytes,
+			&sample.ReadBytes,
+			&sample.WriteBytes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan resource sample: %w", err)
+		}
+		sample.Duration = time.Duration(durationMs) * time.Millisecond
+
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
 // CleanupOldData removes old activities based on retention policy
 func (d *Database) CleanupOldData(retentionDays int) error {
 	cutoff := time.Now().AddDate(0, 0, -retentionDays)
@@ -400,9 +1177,83 @@ func (d *Database) CleanupOldData(retentionDays int) error {
 	rowsAffected, _ := result.RowsAffected()
 	log.Printf("Cleaned up %d old activity records", rowsAffected)
 
+	if _, err := d.db.Exec(`DELETE FROM resource_samples WHERE timestamp < ?`, cutoff); err != nil {
+		return fmt.Errorf("failed to cleanup old resource samples: %w", err)
+	}
+
+	// Screenshots may be shared by several activities and are removed once
+	// none references them
+	query = `
+		DELETE FROM screenshots
+		WHERE timestamp < ?
+		  AND id NOT IN (SELECT screenshot_id FROM activities WHERE screenshot_id IS NOT NULL)
+	`
+	if _, err := d.db.Exec(query, cutoff); err != nil {
+		return fmt.Errorf("failed to cleanup old screenshots: %w", err)
+	}
+
 	return nil
 }
 
+// parseMonitors decodes a stored monitor layout
+func parseMonitors(monitorsJSON sql.NullString) []types.Monitor {
+	if !monitorsJSON.Valid || monitorsJSON.String == "" {
+		return nil
+	}
+
+	var monitors []types.Monitor
+	if err := json.Unmarshal([]byte(monitorsJSON.String), &monitors); err != nil {
+		log.Printf("Failed to unmarshal monitors: %v", err)
+		return nil
+	}
+	return monitors
+}
+
+// parseConnections decodes the stored connections of an activity
+func parseConnections(connectionsJSON sql.NullString) []types.Connection {
+	if !connectionsJSON.Valid || connectionsJSON.String == "" {
+		return nil
+	}
+
+	var connections []types.Connection
+	if err := json.Unmarshal([]byte(connectionsJSON.String), &connections); err != nil {
+		log.Printf("Failed to unmarshal connections: %v", err)
+		return nil
+	}
+	return connections
+}
+
+// marshalCandidates encodes category candidates, storing none as NULL
+func marshalCandidates(candidates []types.CategoryCandidate) (sql.NullString, error) {
+	if len(candidates) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(candidates)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal category candidates: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// parseCandidates decodes stored category candidates
+func parseCandidates(candidatesJSON sql.NullString) []types.CategoryCandidate {
+	if !candidatesJSON.Valid || candidatesJSON.String == "" {
+		return nil
+	}
+
+	var candidates []types.CategoryCandidate
+	if err := json.Unmarshal([]byte(candidatesJSON.String), &candidates); err != nil {
+		log.Printf("Failed to unmarshal category candidates: %v", err)
+		return nil
+	}
+	return candidates
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 // formatDuration formats a duration in a human-readable format
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
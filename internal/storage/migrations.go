@@ -3,6 +3,7 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"log"
 )
 
 // initSchema initializes the database schema
@@ -19,9 +20,65 @@ func (d *Database) initSchema() error {
 		}
 	}
 
+	return d.upgradeSchema()
+}
+
+// upgradeSchema applies the schema upgrades newer than the stored schema version
+func (d *Database) upgradeSchema() error {
+	version, err := d.GetSchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version - 1; i < len(schemaUpgrades); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin schema upgrade: %w", err)
+		}
+
+		for _, statement := range schemaUpgrades[i] {
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to upgrade schema to version %d: %w", i+2, err)
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE settings SET value = ?, updated_at = datetime('now') WHERE key = 'schema_version'", i+2); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update schema version: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit schema upgrade: %w", err)
+		}
+	}
+
+	if version < screenshotsMovedVersion && len(schemaUpgrades)+1 >= screenshotsMovedVersion {
+		d.reclaimScreenshotSpace()
+	}
 	return nil
 }
 
+// screenshotsMovedVersion is the schema version that moved screenshots from
+// the activities table to the screenshots table
+const screenshotsMovedVersion = 10
+
+// reclaimScreenshotSpace rebuilds the database file after screenshots were
+// moved out of the activities table. SQLite keeps the pages the old copies
+// used until VACUUM, so without it the move would double their size on disk.
+// Failing to reclaim the space is logged; the database is usable either way.
+func (d *Database) reclaimScreenshotSpace() {
+	var moved bool
+	if err := d.db.QueryRow("SELECT EXISTS (SELECT 1 FROM screenshots)").Scan(&moved); err != nil || !moved {
+		return
+	}
+
+	log.Printf("Reclaiming the space of moved screenshots, this may take a while")
+	if _, err := d.db.Exec("VACUUM"); err != nil {
+		log.Printf("Failed to reclaim screenshot space, run VACUUM on the database to do so: %v", err)
+	}
+}
+
 // migrations contains all database migrations
 var migrations = []string{
 	// Main activities table
@@ -111,7 +168,121 @@ var migrations = []string{
 	`INSERT OR IGNORE INTO settings (key, value) VALUES 
 		('schema_version', '1'),
 		('created_at', datetime('now')),
-		('last_cleanup', datetime('now'));`,
+		('last_cleanup', datetime('now')),
+		('title_salt', lower(hex(randomblob(16))));`,
+}
+
+// schemaUpgrades contains the changes made after the initial schema.
+// Entry i upgrades the database from schema version i+1 to i+2.
+var schemaUpgrades = [][]string{
+	// Version 2: exact focus segment boundaries
+	{
+		`ALTER TABLE activities ADD COLUMN start_time DATETIME;`,
+		`ALTER TABLE activities ADD COLUMN end_time DATETIME;`,
+	},
+	// Version 3: monitor of the focused window and monitor layout
+	{
+		`ALTER TABLE activities ADD COLUMN monitor TEXT;`,
+		`ALTER TABLE activities ADD COLUMN monitor_layout TEXT;`,
+	},
+	// Version 4: git repository of the focused window
+	{
+		`ALTER TABLE activities ADD COLUMN repo_root TEXT;`,
+		`ALTER TABLE activities ADD COLUMN repo_remote TEXT;`,
+		`ALTER TABLE activities ADD COLUMN repo_branch TEXT;`,
+		`CREATE INDEX IF NOT EXISTS idx_activities_repo_root ON activities(repo_root);`,
+	},
+	// Version 5: active browser tab
+	{
+		`ALTER TABLE activities ADD COLUMN tab_url TEXT;`,
+		`ALTER TABLE activities ADD COLUMN tab_title TEXT;`,
+		`ALTER TABLE activities ADD COLUMN tab_domain TEXT;`,
+		`CREATE INDEX IF NOT EXISTS idx_activities_tab_domain ON activities(tab_domain);`,
+	},
+	// Version 6: per-application resource usage
+	{
+		`CREATE TABLE IF NOT EXISTS resource_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			app_name TEXT NOT NULL,
+			process_id INTEGER NOT NULL,
+			process_count INTEGER NOT NULL,
+			cpu_percent REAL NOT NULL,
+			rss_bytes INTEGER NOT NULL,
+			read_bytes INTEGER NOT NULL,
+			write_bytes INTEGER NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_resource_samples_timestamp ON resource_samples(timestamp);`,
+		`CREATE INDEX IF NOT EXISTS idx_resource_samples_app_name ON resource_samples(app_name);`,
+	},
+	// Version 7: network connections of the focused window
+	{
+		`ALTER TABLE activities ADD COLUMN connections TEXT;`,
+	},
+	// Version 8: enricher metadata
+	{
+		`CREATE TABLE IF NOT EXISTS activity_metadata (
+			activity_id INTEGER NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL, -- JSON
+			PRIMARY KEY (activity_id, key)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_activity_metadata_key ON activity_metadata(key);`,
+	},
+	// Version 9: screenshot thumbnails
+	{
+		`ALTER TABLE activities ADD COLUMN thumbnail BLOB;`,
+	},
+	// Version 10: screenshots stored once and referenced by activities, so
+	// near-identical screenshots can share an image. Existing screenshots
+	// keep the ID of their activity.
+	{
+		`CREATE TABLE IF NOT EXISTS screenshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp DATETIME NOT NULL,
+			hash INTEGER, -- 64-bit perceptual hash, NULL for screenshots from before hashing
+			image BLOB NOT NULL,
+			thumbnail BLOB
+		);`,
+		`ALTER TABLE activities ADD COLUMN screenshot_id INTEGER REFERENCES screenshots(id);`,
+		`INSERT INTO screenshots (id, timestamp, image, thumbnail)
+			SELECT id, timestamp, screenshot, thumbnail FROM activities WHERE screenshot IS NOT NULL;`,
+		`UPDATE activities SET screenshot_id = id, screenshot = NULL, thumbnail = NULL
+			WHERE screenshot IS NOT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_activities_screenshot_id ON activities(screenshot_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_screenshots_timestamp ON screenshots(timestamp);`,
+	},
+	// Version 11: categories replaced by recategorization
+	{
+		`CREATE TABLE IF NOT EXISTS category_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			activity_id INTEGER NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+			category TEXT NOT NULL,
+			confidence REAL NOT NULL,
+			replaced_at DATETIME NOT NULL,
+			reason TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_category_history_activity_id ON category_history(activity_id);`,
+	},
+	// Version 12: ranked category candidates
+	{
+		`ALTER TABLE activities ADD COLUMN candidates TEXT;`,
+	},
+	// Version 13: categories set by hand, and the corrections they made to
+	// the categorizer
+	{
+		`ALTER TABLE activities ADD COLUMN manual INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE TABLE IF NOT EXISTS corrections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			activity_id INTEGER NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+			previous_category TEXT NOT NULL,
+			previous_confidence REAL NOT NULL,
+			category TEXT NOT NULL,
+			corrected_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_corrections_activity_id ON corrections(activity_id);`,
+	},
 }
 
 // GetSchemaVersion returns the current schema version
@@ -130,6 +301,16 @@ func (d *Database) UpdateSchemaVersion(version int) error {
 	return err
 }
 
+// GetSetting returns the value of a runtime setting
+func (d *Database) GetSetting(key string) (string, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err != nil {
+		return "", fmt.Errorf("failed to read setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
 // Vacuum optimizes the database
 func (d *Database) Vacuum() error {
 	_, err := d.db.Exec("VACUUM;")
@@ -170,6 +351,28 @@ func (d *Database) GetDatabaseStats() (map[string]interface{}, error) {
 	}
 	stats["unique_apps"] = uniqueApps
 
+	// Screenshot storage; each activity referencing an earlier screenshot
+	// saved storing a copy of it
+	var screenshotCount, screenshotBytes, referencedBytes int64
+	err = d.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(LENGTH(image) + COALESCE(LENGTH(thumbnail), 0)), 0)
+		FROM screenshots
+	`).Scan(&screenshotCount, &screenshotBytes)
+	if err != nil {
+		return nil, err
+	}
+	err = d.db.QueryRow(`
+		SELECT COALESCE(SUM(LENGTH(s.image) + COALESCE(LENGTH(s.thumbnail), 0)), 0)
+		FROM activities a
+		JOIN screenshots s ON s.id = a.screenshot_id
+	`).Scan(&referencedBytes)
+	if err != nil {
+		return nil, err
+	}
+	stats["screenshots_stored"] = screenshotCount
+	stats["screenshot_bytes"] = screenshotBytes
+	stats["screenshot_bytes_saved"] = max(referencedBytes-screenshotBytes, 0)
+
 	// Get database size (approximation)
 	var pageCount, pageSize int
 	d.db.QueryRow("PRAGMA page_count").Scan(&pageCount)
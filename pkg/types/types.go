@@ -2,6 +2,10 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -13,7 +17,35 @@ type Window struct {
 	IsActive   bool      `json:"is_active"`
 	LastActive time.Time `json:"last_active"`
 	Position   Rectangle `json:"position"`
-	Monitor    int       `json:"monitor"`
+	// Monitor is the index of the monitor holding most of the window, or
+	// NoMonitor when the window is on none of them
+	Monitor int `json:"monitor"`
+	// Process describes the process owning the window, when it could be read
+	Process *ProcessInfo `json:"process,omitempty"`
+	// Command is the foreground command of a terminal window, e.g. "go test ./..."
+	Command string `json:"command,omitempty"`
+	// Connections are the remote endpoints of the window's process tree
+	Connections []Connection `json:"connections,omitempty"`
+	// Redacted is set when the privacy filter hid the title of a private
+	// window; enrichers must not attach details to redacted windows
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// Connection is a remote endpoint of established TCP connections
+type Connection struct {
+	RemoteIP   string `json:"remote_ip"`
+	RemotePort int    `json:"remote_port"`
+	Host       string `json:"host,omitempty"` // Name from the hosts file
+	Count      int    `json:"count"`          // Open sockets to this endpoint
+}
+
+// ProcessInfo describes the process behind a window
+type ProcessInfo struct {
+	Executable  string    `json:"exe"`
+	CommandLine []string  `json:"cmdline"`
+	WorkingDir  string    `json:"cwd"`
+	ParentPID   int       `json:"ppid"`
+	StartTime   time.Time `json:"start_time"`
 }
 
 // Rectangle represents window position and size
@@ -24,31 +56,102 @@ type Rectangle struct {
 	Height int `json:"height"`
 }
 
+// Monitor describes one monitor of the screen layout
+type Monitor struct {
+	Index   int       `json:"index"`
+	Name    string    `json:"name"`
+	Bounds  Rectangle `json:"bounds"`
+	Primary bool      `json:"primary"`
+}
+
+// NoMonitor is the Monitor of a window overlapping no monitor, e.g. one
+// minimized or moved off screen
+const NoMonitor = -1
+
 // Activity represents a captured workspace state
 type Activity struct {
-	ID            int64     `json:"id"`
-	Timestamp     time.Time `json:"timestamp"`
-	AppName       string    `json:"app_name"`
-	WindowTitle   string    `json:"window_title"`
-	ProcessID     int       `json:"process_id"`
-	IsActive      bool      `json:"is_active"`
-	FocusDuration int       `json:"focus_duration"`
-	TotalWindows  int       `json:"total_windows"`
-	AllWindows    []Window  `json:"all_windows"`
-	Category      string    `json:"category"`
-	Confidence    float64   `json:"confidence"`
-	Screenshot    []byte    `json:"-"`              // Don't serialize screenshots in API
-	HasScreenshot bool      `json:"has_screenshot"` // Indicate if screenshot exists
+	ID            int64               `json:"id"`
+	Timestamp     time.Time           `json:"timestamp"`
+	StartTime     time.Time           `json:"start_time"`
+	EndTime       time.Time           `json:"end_time"`
+	AppName       string              `json:"app_name"`
+	WindowTitle   string              `json:"window_title"`
+	ProcessID     int                 `json:"process_id"`
+	IsActive      bool                `json:"is_active"`
+	FocusDuration int                 `json:"focus_duration"`
+	TotalWindows  int                 `json:"total_windows"`
+	AllWindows    []Window            `json:"all_windows"`
+	Monitor       string              `json:"monitor"`               // Monitor holding the focused window
+	Monitors      []Monitor           `json:"monitors"`              // Monitor layout at capture time
+	Repository    *Repository         `json:"repository,omitempty"`  // Git checkout of the focused IDE or terminal
+	Tab           *BrowserTab         `json:"tab,omitempty"`         // Active tab of the focused browser
+	Connections   []Connection        `json:"connections,omitempty"` // Remote endpoints of the focused window
+	Metadata      Metadata            `json:"metadata,omitempty"`    // Added by enrichers
+	Category      string              `json:"category"`
+	Confidence    float64             `json:"confidence"`
+	Candidates    []CategoryCandidate `json:"candidates,omitempty"` // Ranked categories the activity could belong to
+	Manual        bool                `json:"manual"`               // Category set by the user, kept by recategorization
+	Screenshot    []byte              `json:"-"`                    // Don't serialize screenshots in API
+	Thumbnail     []byte              `json:"-"`                    // Downscaled screenshot
+	HasScreenshot bool                `json:"has_screenshot"`       // Indicate if screenshot exists
+	// ScreenshotID is the stored screenshot the activity shows. When saving,
+	// it references an earlier screenshot in place of Screenshot; after
+	// saving a new Screenshot it is the ID it was stored under.
+	ScreenshotID   int64  `json:"screenshot_id,omitempty"`
+	ScreenshotHash uint64 `json:"-"` // Perceptual hash of Screenshot
+	// ResourceSamples are the resource usage of every window's process tree
+	// at capture, stored with the activity
+	ResourceSamples []ResourceSample `json:"-"`
+}
+
+// CategoryCandidate is a category an activity could belong to, with its
+// share of the evidence
+type CategoryCandidate struct {
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
 }
 
 // WorkspaceSnapshot represents complete workspace state at a point in time
 type WorkspaceSnapshot struct {
-	Timestamp    time.Time `json:"timestamp"`
-	ActiveWindow Window    `json:"active_window"`
-	AllWindows   []Window  `json:"all_windows"`
-	WindowCount  int       `json:"window_count"`
-	Category     string    `json:"category"`
-	Screenshot   []byte    `json:"-"`
+	Timestamp    time.Time           `json:"timestamp"`
+	ActiveWindow Window              `json:"active_window"`
+	AllWindows   []Window            `json:"all_windows"`
+	WindowCount  int                 `json:"window_count"`
+	Monitors     []Monitor           `json:"monitors"`
+	Repository   *Repository         `json:"repository,omitempty"`
+	Tab          *BrowserTab         `json:"tab,omitempty"`
+	Metadata     Metadata            `json:"metadata,omitempty"`
+	Category     string              `json:"category"`
+	Confidence   float64             `json:"confidence"`
+	Candidates   []CategoryCandidate `json:"candidates,omitempty"` // Ranked categories the workspace could belong to
+	Screenshot   []byte              `json:"-"`
+	Thumbnail    []byte              `json:"-"`
+	// ScreenshotHash is the perceptual hash of Screenshot; ScreenshotID
+	// references a stored screenshot near-identical to the one captured
+	ScreenshotHash uint64 `json:"-"`
+	ScreenshotID   int64  `json:"-"`
+	// ResourceSamples are the resource usage of every window's process tree
+	ResourceSamples []ResourceSample `json:"-"`
+}
+
+// Metadata holds values added to a snapshot by enrichers, keyed by a name
+// chosen by the enricher. Values must marshal to JSON; after a round trip
+// through storage they are decoded as generic JSON values.
+type Metadata map[string]interface{}
+
+// SetMetadata adds a metadata value to the snapshot
+func (s *WorkspaceSnapshot) SetMetadata(key string, value interface{}) {
+	if s.Metadata == nil {
+		s.Metadata = make(Metadata)
+	}
+	s.Metadata[key] = value
+}
+
+// Repository describes a git checkout
+type Repository struct {
+	Root   string `json:"root"`
+	Remote string `json:"remote,omitempty"`
+	Branch string `json:"branch"`
 }
 
 // Stats represents aggregated statistics
@@ -57,11 +160,68 @@ type Stats struct {
 	TotalTime       time.Duration            `json:"total_time"`
 	ByApp           map[string]time.Duration `json:"by_app"`
 	ByCategory      map[string]time.Duration `json:"by_category"`
+	ByMonitor       map[string]*MonitorUsage `json:"by_monitor"`
+	ByRepo          map[string]*RepoUsage    `json:"by_repo"`
+	ByDomain        map[string]time.Duration `json:"by_domain"`
 	Patterns        []Pattern                `json:"patterns"`
 	ContextSwitches int                      `json:"context_switches"`
 	LongestFocus    time.Duration            `json:"longest_focus"`
 }
 
+// MonitorUsage breaks down time spent on one monitor
+type MonitorUsage struct {
+	// FocusedTime is time the focused window was on this monitor
+	FocusedTime time.Duration `json:"focused_time"`
+	// ReferenceTime is time this monitor showed windows while focus was on
+	// another monitor, e.g. documentation next to the editor
+	ReferenceTime time.Duration            `json:"reference_time"`
+	ReferenceApps map[string]time.Duration `json:"reference_apps"`
+}
+
+// BrowserTab is the active tab of a browser as reported by the browser
+// extension through the native messaging host
+type BrowserTab struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	TabID     int       `json:"tab_id"`
+	Incognito bool      `json:"incognito"`
+	Audible   bool      `json:"audible"`
+	Browser   string    `json:"browser,omitempty"`
+	PID       int       `json:"pid,omitempty"` // Browser process, filled in by the native host
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Domain returns the host of the tab's URL without a leading "www."
+func (t *BrowserTab) Domain() string {
+	parsed, err := url.Parse(t.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(parsed.Hostname(), "www.")
+}
+
+// ResourceSample is the resource usage of a window's process tree over the
+// interval ending at Timestamp
+type ResourceSample struct {
+	ID         int64         `json:"id"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Duration   time.Duration `json:"duration"` // Length of the sampled interval
+	AppName    string        `json:"app_name"`
+	ProcessID  int           `json:"pid"`
+	Processes  int           `json:"processes"`   // Processes in the tree
+	CPUPercent float64       `json:"cpu_percent"` // 100 per fully used core
+	RSSBytes   uint64        `json:"rss_bytes"`
+	ReadBytes  uint64        `json:"read_bytes"` // Read from storage during the interval
+	WriteBytes uint64        `json:"write_bytes"`
+}
+
+// RepoUsage breaks down time spent in one repository, keyed by its root
+type RepoUsage struct {
+	Remote    string                   `json:"remote,omitempty"`
+	TotalTime time.Duration            `json:"total_time"`
+	ByBranch  map[string]time.Duration `json:"by_branch"`
+}
+
 // Pattern represents a common window combination
 type Pattern struct {
 	Name           string        `json:"name"`
@@ -98,22 +258,25 @@ type Session struct {
 
 // CurrentWorkspace represents real-time workspace state
 type CurrentWorkspace struct {
-	ActiveWindow    Window    `json:"active_window"`
-	AllWindows      []Window  `json:"all_windows"`
-	WindowCount     int       `json:"window_count"`
-	Category        string    `json:"category"`
-	FocusTime       string    `json:"focus_time"`
-	ContextSwitches int       `json:"context_switches"`
-	Timestamp       time.Time `json:"timestamp"`
+	ActiveWindow    Window              `json:"active_window"`
+	AllWindows      []Window            `json:"all_windows"`
+	WindowCount     int                 `json:"window_count"`
+	Monitors        []Monitor           `json:"monitors"`
+	Category        string              `json:"category"`
+	Candidates      []CategoryCandidate `json:"candidates,omitempty"`
+	FocusTime       string              `json:"focus_time"`
+	ContextSwitches int                 `json:"context_switches"`
+	Timestamp       time.Time           `json:"timestamp"`
 }
 
 // Configuration types
 type Config struct {
-	Tracking *TrackingConfig `json:"tracking" yaml:"tracking"`
-	Privacy  *PrivacyConfig  `json:"privacy" yaml:"privacy"`
-	Server   *ServerConfig   `json:"server" yaml:"server"`
-	Storage  *StorageConfig  `json:"storage" yaml:"storage"`
-	AI       *AIConfig       `json:"ai" yaml:"ai"`
+	Tracking       *TrackingConfig       `json:"tracking" yaml:"tracking"`
+	Privacy        *PrivacyConfig        `json:"privacy" yaml:"privacy"`
+	Server         *ServerConfig         `json:"server" yaml:"server"`
+	Storage        *StorageConfig        `json:"storage" yaml:"storage"`
+	AI             *AIConfig             `json:"ai" yaml:"ai"`
+	Categorization *CategorizationConfig `json:"categorization" yaml:"categorization"`
 }
 
 type TrackingConfig struct {
@@ -121,18 +284,208 @@ type TrackingConfig struct {
 	ScreenshotInterval time.Duration `json:"screenshot_interval" yaml:"screenshot_interval"`
 	CaptureScreenshots bool          `json:"capture_screenshots" yaml:"capture_screenshots"`
 	TrackAllWindows    bool          `json:"track_all_windows" yaml:"track_all_windows"`
+	Backend            string        `json:"backend" yaml:"backend"`
+	Mode               string        `json:"mode" yaml:"mode"`
+	IdleThreshold      time.Duration `json:"idle_threshold" yaml:"idle_threshold" mapstructure:"idle_threshold"`
+	SampleResources    bool          `json:"sample_resources" yaml:"sample_resources" mapstructure:"sample_resources"`
+	ScreenshotFormat   string        `json:"screenshot_format" yaml:"screenshot_format" mapstructure:"screenshot_format"`
+	ScreenshotQuality  int           `json:"screenshot_quality" yaml:"screenshot_quality" mapstructure:"screenshot_quality"`
+	ScreenshotMaxSize  int           `json:"screenshot_max_size" yaml:"screenshot_max_size" mapstructure:"screenshot_max_size"`
+	ThumbnailSize      int           `json:"thumbnail_size" yaml:"thumbnail_size" mapstructure:"thumbnail_size"`
+	// ScreenshotDedupDistance is the largest perceptual hash distance at which
+	// a screenshot counts as a duplicate of the last stored one; negative
+	// disables deduplication
+	ScreenshotDedupDistance int `json:"screenshot_dedup_distance" yaml:"screenshot_dedup_distance" mapstructure:"screenshot_dedup_distance"`
+	// Schedule limits tracking to working hours
+	Schedule ScheduleConfig `json:"schedule" yaml:"schedule" mapstructure:"schedule"`
+}
+
+// ScheduleConfig limits tracking to weekly time windows. Weekly maps
+// weekday names to windows such as "09:00-17:30" in Timezone, the local
+// zone when empty; Holidays are YYYY-MM-DD dates without tracking.
+type ScheduleConfig struct {
+	Enabled  bool                `json:"enabled" yaml:"enabled"`
+	Timezone string              `json:"timezone" yaml:"timezone"`
+	Weekly   map[string][]string `json:"weekly" yaml:"weekly"`
+	Holidays []string            `json:"holidays" yaml:"holidays"`
+}
+
+// ScheduleStatus reports whether tracking runs under the working-hours
+// schedule and when that next changes
+type ScheduleStatus struct {
+	Enabled        bool       `json:"enabled"`
+	InSchedule     bool       `json:"in_schedule"`
+	Tracking       bool       `json:"tracking"` // In schedule or overridden
+	NextTransition *time.Time `json:"next_transition,omitempty"`
+	OverrideUntil  *time.Time `json:"override_until,omitempty"`
+}
+
+// Screenshot formats selectable through tracking.screenshot_format
+const (
+	ScreenshotJPEG = "jpeg" // Lossy, sized by screenshot_quality
+	ScreenshotPNG  = "png"  // Lossless
+)
+
+// Window manager backends selectable through tracking.backend
+const (
+	BackendAuto     = "auto"     // Pick the best backend for the current session
+	BackendX11      = "x11"      // Native X11 protocol connection
+	BackendExec     = "exec"     // xprop/wmctrl/xwininfo command line tools
+	BackendSway     = "sway"     // sway IPC socket ($SWAYSOCK)
+	BackendHyprland = "hyprland" // Hyprland IPC sockets
+)
+
+// Categories recorded for time nobody was working
+const (
+	CategoryIdle   = "Idle"   // No keyboard or mouse input
+	CategoryAway   = "Away"   // Machine suspended
+	CategoryLocked = "Locked" // Screen locked
+	CategoryPaused = "Paused" // Tracking paused by the user
+)
+
+// RecategorizeStatus reports a job categorizing stored activities again
+type RecategorizeStatus struct {
+	Running    bool       `json:"running"`
+	DryRun     bool       `json:"dry_run"` // Changes are counted, not stored
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	Total      int        `json:"total"`     // Activities in the range
+	Processed  int        `json:"processed"` // Activities categorized so far
+	Changed    int        `json:"changed"`   // Activities given a new category or confidence
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// CategoryChange replaces the category of a stored activity
+type CategoryChange struct {
+	ActivityID         int64
+	PreviousCategory   string
+	PreviousConfidence float64
+	Category           string
+	Confidence         float64
+	Candidates         []CategoryCandidate
+}
+
+// MaxRelabelIDs bounds the activity IDs of one relabel request, keeping its
+// query within SQLite's limit on parameters
+const MaxRelabelIDs = 500
+
+// RelabelRequest sets the category of activities by hand, choosing them by
+// ID or by time range
+type RelabelRequest struct {
+	IDs      []int64    `json:"ids,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+	Category string     `json:"category"`
+}
+
+// Validate checks that a relabel request names a category and exactly one
+// way of choosing activities
+func (r *RelabelRequest) Validate() error {
+	switch strings.TrimSpace(r.Category) {
+	case "":
+		return errors.New("a category is required")
+	case CategoryIdle, CategoryAway, CategoryLocked, CategoryPaused:
+		return errors.New("time away from work cannot be set by hand")
+	}
+
+	byRange := r.From != nil || r.To != nil
+	if len(r.IDs) > 0 && byRange {
+		return errors.New("choose activities by IDs or by time range, not both")
+	}
+	if !byRange {
+		if len(r.IDs) == 0 {
+			return errors.New("activity IDs or a time range are required")
+		}
+		if len(r.IDs) > MaxRelabelIDs {
+			return fmt.Errorf("at most %d activity IDs can be relabelled at once, use a time range", MaxRelabelIDs)
+		}
+		return nil
+	}
+	if r.From == nil || r.To == nil {
+		return errors.New("a time range needs both from and to")
+	}
+	if !r.From.Before(*r.To) {
+		return errors.New("the start of the range must be before its end")
+	}
+	return nil
 }
 
+// RelabelResult reports how many activities a relabel request changed
+type RelabelResult struct {
+	Relabeled int `json:"relabeled"`
+}
+
+// PauseState tells whether tracking is paused by the user
+type PauseState struct {
+	Paused bool       `json:"paused"`
+	Since  *time.Time `json:"since,omitempty"`
+	Until  *time.Time `json:"until,omitempty"` // Nil when paused until resumed
+}
+
+// Capture modes selectable through tracking.mode
+const (
+	ModePoll   = "poll"   // Sample the focused window every interval
+	ModeEvents = "events" // Record focus changes as they happen
+)
+
 type PrivacyConfig struct {
-	ExcludeApps    []string `json:"exclude_apps" yaml:"exclude_apps"`
-	ExcludeTitles  []string `json:"exclude_titles" yaml:"exclude_titles"`
-	BlurSensitive  bool     `json:"blur_sensitive" yaml:"blur_sensitive"`
-	AutoDeleteDays int      `json:"auto_delete_after" yaml:"auto_delete_after"`
+	ExcludeApps        []string `json:"exclude_apps" yaml:"exclude_apps"`
+	ExcludeTitles      []string `json:"exclude_titles" yaml:"exclude_titles"`
+	ExcludeExecutables []string `json:"exclude_executables" yaml:"exclude_executables" mapstructure:"exclude_executables"`
+	ExcludeIncognito   bool     `json:"exclude_incognito" yaml:"exclude_incognito" mapstructure:"exclude_incognito"`
+	TrackConnections   bool     `json:"track_connections" yaml:"track_connections" mapstructure:"track_connections"`
+	BlurSensitive      bool     `json:"blur_sensitive" yaml:"blur_sensitive"`
+	AutoDeleteDays     int      `json:"auto_delete_after" yaml:"auto_delete_after"`
+	// RedactionRules rewrite parts of window titles, applied in order after
+	// ExcludeTitles
+	RedactionRules []RedactionRule `json:"redaction_rules" yaml:"redaction_rules" mapstructure:"redaction_rules"`
+}
+
+// RedactionRule redacts window titles matching a regular expression or a
+// built-in detector
+type RedactionRule struct {
+	Pattern  string `json:"pattern,omitempty" yaml:"pattern,omitempty"`   // Case-insensitive regular expression
+	Detector string `json:"detector,omitempty" yaml:"detector,omitempty"` // Built-in pattern, used instead of Pattern
+	Action   string `json:"action" yaml:"action"`
+	// Replacement replaces each match for the replace action and may
+	// reference capture groups as $1 or ${name}
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// Apps limits the rule to windows of these applications; it applies to
+	// all windows when empty
+	Apps []string `json:"apps,omitempty" yaml:"apps,omitempty"`
 }
 
+// Redaction actions
+const (
+	RedactMask    = "mask"    // Replace each match with a placeholder
+	RedactReplace = "replace" // Replace each match with Replacement
+	RedactHash    = "hash"    // Replace the whole title with a salted hash
+	RedactDrop    = "drop"    // Drop the window like an excluded app
+)
+
+// Built-in redaction detectors
+const (
+	DetectEmail      = "email"       // Email addresses
+	DetectToken      = "token"       // API keys and access tokens
+	DetectCreditCard = "credit_card" // Card numbers passing the Luhn check
+	DetectURLQuery   = "url_query"   // Query strings of URLs
+)
+
 type ServerConfig struct {
 	Port string `json:"port" yaml:"port"`
 	Host string `json:"host" yaml:"host"`
+	// DashboardOrigins are the web origins allowed to change state through
+	// the API, e.g. pause or resume tracking
+	DashboardOrigins []string `json:"dashboard_origins" yaml:"dashboard_origins" mapstructure:"dashboard_origins"`
+}
+
+// CategorizationConfig controls how categories are reported
+type CategorizationConfig struct {
+	// SplitTime apportions the time of an activity across its category
+	// candidates by score in stats, instead of giving it all to its category
+	SplitTime bool `json:"split_time" yaml:"split_time" mapstructure:"split_time"`
 }
 
 type StorageConfig struct {
@@ -154,12 +507,17 @@ type WindowManager interface {
 	GetFocusDuration() time.Duration
 }
 
-// Rule for categorization
+// Rule for categorization. Rules match either the windows or, with
+// SnapshotMatcher, the whole snapshot including enricher metadata.
 type Rule struct {
-	Name     string
-	Priority int
-	Matcher  func(windows []Window) bool
-	Category string
+	Name            string
+	Priority        int
+	Matcher         func(windows []Window) bool
+	SnapshotMatcher func(snapshot *WorkspaceSnapshot) bool
+	Category        string
+	// Explain describes whether each condition of the rule holds, one line
+	// per condition. Rules from rules files have it.
+	Explain func(snapshot *WorkspaceSnapshot) []string
 }
 
 // Error types
@@ -206,8 +564,12 @@ func (a *Activity) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		*Alias
 		Timestamp string `json:"timestamp"`
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
 	}{
 		Alias:     (*Alias)(a),
 		Timestamp: a.Timestamp.Format(time.RFC3339),
+		StartTime: a.StartTime.Format(time.RFC3339Nano),
+		EndTime:   a.EndTime.Format(time.RFC3339Nano),
 	})
 }